@@ -29,6 +29,16 @@ func main() {
 	// dbeatOptions.Privacy.UserIDHash = true
 	// dbeatOptions.Privacy.UserAgentSalt = false
 
+	// Use chi route patterns such as /users/{id} as the event source
+	dbeatOptions.Source.RoutePatterns = []databeat.RoutePatternFunc{
+		func(r *http.Request) string {
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				return rctx.RoutePattern()
+			}
+			return ""
+		},
+	}
+
 	dbeat, err := databeat.NewDatabeatClient(databeatHost, authToken, logger, dbeatOptions)
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,214 @@
+package databeat
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	ReasonQueueOverflow   = "queue_overflow"
+	ReasonRequeueOverflow = "requeue_overflow"
+	ReasonNotRunning      = "not_running"
+	ReasonBot             = "bot"
+	ReasonValidation      = "validation"
+	ReasonFlatten         = "flatten"
+	ReasonReplacedEvent   = "replaced_event"
+	ReasonTick            = "tick"
+	ReasonRawEvents       = "raw_events"
+)
+
+// Alert is an aggregated report of events which were invalid, dropped or
+// failed to flush, for a single reason since the last alert of that reason.
+type Alert struct {
+	Reason string
+	Count  int
+	Events []string          // sample of event names
+	Errors []ValidationError // sample of invalid or dropped props
+	Err    error             // last error, for flush errors
+	Since  time.Time
+}
+
+type AlertOptions struct {
+	// OnInvalidEvent is called for events with an invalid type or props,
+	// by validation reason, ie. ReasonUnknownEvent or ReasonEnum. The
+	// invalid props are sampled on Alert.Errors.
+	OnInvalidEvent func(alert Alert)
+
+	// OnDrop is called for events dropped before being sent, by reason,
+	// ie. ReasonQueueOverflow, ReasonBot or ReasonValidation. Nested props
+	// dropped by Options.Flatten are reported with ReasonFlatten, where
+	// Count is the number of props, sampled on Alert.Errors. Raw events
+	// replaced by an enricher are reported with ReasonReplacedEvent.
+	OnDrop func(alert Alert)
+
+	// OnFlushError is called for events which failed to be sent after
+	// retries, by endpoint, ie. ReasonTick or ReasonRawEvents.
+	OnFlushError func(alert Alert)
+
+	// Interval is the minimum interval between two alerts of the same
+	// reason. Occurrences in between are aggregated into the next alert,
+	// so a hot loop does not produce thousands of callbacks.
+	Interval time.Duration
+}
+
+var DefaultAlertOptions = AlertOptions{
+	OnInvalidEvent: nil, OnDrop: nil, OnFlushError: nil, Interval: 1 * time.Minute,
+}
+
+const maxAlertSamples = 10
+
+type alertKey struct {
+	kind   uint8
+	reason string
+}
+
+const (
+	alertInvalid uint8 = iota
+	alertDrop
+	alertFlushError
+)
+
+type alertState struct {
+	pending   Alert
+	lastFired time.Time
+	timer     *time.Timer
+}
+
+// alerter aggregates alerts per kind and reason, and rate limits the
+// callbacks. Aggregated alerts are fired once the interval has elapsed.
+// Callbacks are called on their own goroutine, so reporting is safe while
+// holding the client lock.
+type alerter struct {
+	opts   AlertOptions
+	mu     sync.Mutex
+	states map[alertKey]*alertState
+}
+
+func newAlerter(opts AlertOptions) *alerter {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultAlertOptions.Interval
+	}
+	return &alerter{opts: opts, states: map[alertKey]*alertState{}}
+}
+
+func (a *alerter) callback(kind uint8) func(Alert) {
+	switch kind {
+	case alertInvalid:
+		return a.opts.OnInvalidEvent
+	case alertDrop:
+		return a.opts.OnDrop
+	default:
+		return a.opts.OnFlushError
+	}
+}
+
+func (a *alerter) invalid(errs []ValidationError) {
+	if a.opts.OnInvalidEvent == nil {
+		return
+	}
+	for _, err := range errs {
+		a.report(alertInvalid, err.Reason, 1, []string{err.Event}, nil, err)
+	}
+}
+
+func (a *alerter) drop(reason string, count int, names []string, errs ...ValidationError) {
+	a.report(alertDrop, reason, count, names, nil, errs...)
+}
+
+func (a *alerter) flushError(reason string, count int, names []string, err error) {
+	a.report(alertFlushError, reason, count, names, err)
+}
+
+func (a *alerter) report(kind uint8, reason string, count int, names []string, err error, validationErrs ...ValidationError) {
+	fn := a.callback(kind)
+	if fn == nil || count <= 0 {
+		return
+	}
+	now := time.Now()
+
+	a.mu.Lock()
+	key := alertKey{kind, reason}
+	st, ok := a.states[key]
+	if !ok {
+		st = &alertState{}
+		a.states[key] = st
+	}
+	if st.pending.Count == 0 {
+		st.pending = Alert{Reason: reason, Since: now}
+	}
+	st.pending.Count += count
+	for _, name := range names {
+		if len(st.pending.Events) >= maxAlertSamples {
+			break
+		}
+		if !contains(st.pending.Events, name) {
+			st.pending.Events = append(st.pending.Events, name)
+		}
+	}
+	for _, verr := range validationErrs {
+		if len(st.pending.Errors) >= maxAlertSamples {
+			break
+		}
+		if !slices.Contains(st.pending.Errors, verr) {
+			st.pending.Errors = append(st.pending.Errors, verr)
+		}
+	}
+	if err != nil {
+		st.pending.Err = err
+	}
+
+	var alert Alert
+	fire := now.Sub(st.lastFired) >= a.opts.Interval
+	if fire {
+		alert = st.pending
+		st.pending = Alert{}
+		st.lastFired = now
+	} else if st.timer == nil {
+		st.timer = time.AfterFunc(st.lastFired.Add(a.opts.Interval).Sub(now), func() {
+			a.fire(key)
+		})
+	}
+	a.mu.Unlock()
+
+	if fire {
+		go fn(alert)
+	}
+}
+
+// fire calls back with the aggregated alert of the key.
+func (a *alerter) fire(key alertKey) {
+	a.mu.Lock()
+	st := a.states[key]
+	alert := st.pending
+	st.pending = Alert{}
+	st.lastFired = time.Now()
+	st.timer = nil
+	a.mu.Unlock()
+
+	if alert.Count > 0 {
+		a.callback(key.kind)(alert)
+	}
+}
+
+func eventNames(events []*Event) []string {
+	names := make([]string, 0, min(len(events), maxAlertSamples))
+	for _, ev := range events {
+		if len(names) >= maxAlertSamples {
+			break
+		}
+		names = append(names, ev.Event)
+	}
+	return names
+}
+
+func rawEventNames(events []*RawEvent) []string {
+	names := make([]string, 0, min(len(events), maxAlertSamples))
+	for _, ev := range events {
+		if len(names) >= maxAlertSamples {
+			break
+		}
+		names = append(names, ev.Event)
+	}
+	return names
+}
@@ -0,0 +1,28 @@
+package databeat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertValidationErrors(t *testing.T) {
+	alerts := make(chan Alert, 1)
+	opts := DefaultOptions
+	opts.Alerts.OnInvalidEvent = func(alert Alert) { alerts <- alert }
+	opts.Validation.Schemas = map[string]EventSchema{
+		"LOGIN": {Props: map[string]PropRule{"method": {Enum: []string{"email", "oauth"}}}},
+	}
+	dbeat := newTestClient(t, opts)
+
+	dbeat.TrackEvent(From{}, Event{Event: "LOGIN", Props: map[string]string{"method": "sms"}})
+
+	select {
+	case alert := <-alerts:
+		want := ValidationError{Event: "LOGIN", Prop: "method", Reason: ReasonEnum}
+		if alert.Reason != ReasonEnum || len(alert.Errors) != 1 || alert.Errors[0] != want {
+			t.Errorf("unexpected alert %+v", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alert")
+	}
+}
@@ -0,0 +1,204 @@
+package databeat
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Attribution is the marketing attribution of a request, derived from
+// its query parameters and Referer header.
+type Attribution struct {
+	Params   map[string]string
+	Referrer string
+	Channel  string
+}
+
+// AttributionParams are the query parameters copied to event props.
+var AttributionParams = []string{
+	"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "utm_id",
+	"gclid", "gbraid", "wbraid", "dclid", "fbclid", "msclkid", "ttclid", "twclid", "li_fat_id",
+	"ref",
+}
+
+const (
+	ChannelDirect   = "direct"
+	ChannelSearch   = "search"
+	ChannelSocial   = "social"
+	ChannelEmail    = "email"
+	ChannelReferral = "referral"
+)
+
+var searchEngines = []string{
+	"google", "bing", "yahoo", "duckduckgo", "baidu", "yandex", "ecosia", "ask", "naver", "seznam", "qwant", "brave",
+}
+
+var socialNetworks = []string{
+	"facebook", "fb", "instagram", "t.co", "twitter", "x.com", "linkedin", "lnkd.in", "reddit", "pinterest",
+	"tiktok", "youtube", "youtu.be", "whatsapp", "t.me", "telegram", "discord", "threads.net", "bsky.app", "mastodon",
+}
+
+var searchClickIDs = []string{"gclid", "gbraid", "wbraid", "dclid", "msclkid"}
+var socialClickIDs = []string{"fbclid", "ttclid", "twclid", "li_fat_id"}
+
+// AttributionFromRequest returns the attribution of the request. Navigation
+// within the same host is not a new touch, and returns false.
+func AttributionFromRequest(r *http.Request) (Attribution, bool) {
+	if r == nil || r.URL == nil {
+		return Attribution{}, false
+	}
+
+	a := Attribution{Params: map[string]string{}}
+	query := r.URL.Query()
+	for _, k := range AttributionParams {
+		if v := query.Get(k); v != "" {
+			a.Params[k] = v
+		}
+	}
+
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Host != "" {
+		a.Referrer = strings.ToLower(ref.Hostname())
+	}
+	if a.Referrer != "" && len(a.Params) == 0 && strings.EqualFold(a.Referrer, hostname(r.Host)) {
+		return Attribution{}, false
+	}
+
+	a.Channel = a.classify()
+	return a, true
+}
+
+func (a Attribution) classify() string {
+	medium := strings.ToLower(a.Params["utm_medium"])
+	source := strings.ToLower(a.Params["utm_source"])
+
+	switch {
+	case strings.Contains(medium, "email") || strings.Contains(medium, "newsletter"):
+		return ChannelEmail
+	case a.hasParam(searchClickIDs) || medium == "cpc" || medium == "ppc" || medium == "organic" || hostMatches(source, searchEngines):
+		return ChannelSearch
+	case a.hasParam(socialClickIDs) || strings.Contains(medium, "social") || hostMatches(source, socialNetworks):
+		return ChannelSocial
+	case a.Referrer == "":
+		if len(a.Params) > 0 {
+			return ChannelReferral
+		}
+		return ChannelDirect
+	case strings.HasPrefix(a.Referrer, "mail.") || strings.Contains(a.Referrer, ".mail.") || strings.HasPrefix(a.Referrer, "outlook."):
+		return ChannelEmail
+	case hostMatches(a.Referrer, searchEngines):
+		return ChannelSearch
+	case hostMatches(a.Referrer, socialNetworks):
+		return ChannelSocial
+	default:
+		return ChannelReferral
+	}
+}
+
+func (a Attribution) hasParam(keys []string) bool {
+	for _, k := range keys {
+		if _, ok := a.Params[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Props returns the attribution as event props, with keys prefixed by `prefix`.
+func (a Attribution) Props(prefix string) map[string]string {
+	props := make(map[string]string, len(a.Params)+2)
+	for k, v := range a.Params {
+		props[prefix+k] = v
+	}
+	if a.Referrer != "" {
+		props[prefix+"referrer"] = a.Referrer
+	}
+	if a.Channel != "" {
+		props[prefix+"channel"] = a.Channel
+	}
+	return props
+}
+
+// hostMatches reports whether a host, or a bare name such as a utm_source,
+// contains one of the names as a dot-separated label sequence.
+func hostMatches(host string, names []string) bool {
+	if host == "" {
+		return false
+	}
+	host = "." + host + "."
+	for _, name := range names {
+		if strings.Contains(host, "."+name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+type AttributionOptions struct {
+	// Enabled adds the attribution props of user requests to events.
+	Enabled bool
+
+	// FirstTouch persists the first attribution seen for a session, and
+	// adds it to every later event of that session with the `ft_` prefix.
+	FirstTouch bool
+
+	// MaxSessions is the number of sessions kept in memory for FirstTouch.
+	MaxSessions int
+}
+
+var DefaultAttributionOptions = AttributionOptions{
+	Enabled: false, FirstTouch: false, MaxSessions: 10_000,
+}
+
+// firstTouchStore is a bounded in-memory map of session ids to their
+// first attribution, evicting the oldest sessions first.
+type firstTouchStore struct {
+	mu      sync.Mutex
+	touches *boundedMap[map[string]string]
+}
+
+func newFirstTouchStore(max int) *firstTouchStore {
+	if max <= 0 {
+		max = DefaultAttributionOptions.MaxSessions
+	}
+	return &firstTouchStore{touches: newBoundedMap[map[string]string](max)}
+}
+
+// get returns the first touch props of the session, storing `props` if
+// the session has not been seen before.
+func (s *firstTouchStore) get(sessionID string, props map[string]string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ft, ok := s.touches.get(sessionID); ok {
+		return ft
+	}
+	if props == nil {
+		return nil
+	}
+	s.touches.set(sessionID, props)
+	return props
+}
+
+func (t *Databeat) setAttributionProps(ev *Event, attribution map[string]string) {
+	var firstTouch map[string]string
+	if t.options.Attribution.FirstTouch && ev.SessionID != nil && *ev.SessionID != "" {
+		firstTouch = t.firstTouch.get(*ev.SessionID, attribution)
+	}
+	setDefaultProps(ev, attribution)
+	if len(firstTouch) > 0 {
+		ft := make(map[string]string, len(firstTouch))
+		for k, v := range firstTouch {
+			ft["ft_"+k] = v
+		}
+		setDefaultProps(ev, ft)
+	}
+}
@@ -0,0 +1,226 @@
+package databeat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/mileusna/useragent"
+)
+
+// BotDetector detects bots and crawlers from the User-Agent, the client
+// address and headless browser heuristics.
+type BotDetector struct {
+	// UserAgentPatterns are case-insensitive substrings of bot User-Agents.
+	UserAgentPatterns []string
+
+	// CrawlerRanges are the networks of known crawlers, see LoadCrawlerRanges.
+	CrawlerRanges []netip.Prefix
+
+	// ClientIP resolves the client address checked against CrawlerRanges.
+	ClientIP ClientIPResolver
+
+	// Headless enables detection of headless browsers by their User-Agent
+	// and client hints.
+	Headless bool
+
+	// MissingAcceptLanguage detects browser User-Agents without an
+	// Accept-Language header as headless. Only enable it for handlers which
+	// are requested by browsers, as API clients often omit the header.
+	MissingAcceptLanguage bool
+}
+
+var DefaultBotDetector = &BotDetector{
+	UserAgentPatterns: []string{
+		"bot/", "bot;", "bot)", "bot-", "bot+", "crawler", "spider", "slurp",
+		"facebookexternalhit", "mediapartners", "feedfetcher", "scrapy",
+	},
+	ClientIP:              DefaultClientIPResolver,
+	Headless:              true,
+	MissingAcceptLanguage: false,
+}
+
+// HTTPClientUserAgentPatterns are the User-Agents of generic HTTP clients
+// and libraries. They are not part of DefaultBotDetector, as they are also
+// used by service-to-service and API callers, but can be appended to
+// BotDetector.UserAgentPatterns of browser facing handlers.
+var HTTPClientUserAgentPatterns = []string{
+	"curl/", "wget/", "python-requests", "python-urllib", "go-http-client",
+	"libwww-perl", "java/", "httpclient", "axios/", "node-fetch",
+}
+
+var headlessPatterns = []string{
+	"headlesschrome", "phantomjs", "puppeteer", "playwright", "selenium", "lighthouse",
+}
+
+// IsBot reports whether the request was made by a bot, and why.
+func (d *BotDetector) IsBot(r *http.Request) (bool, string) {
+	if d == nil || r == nil {
+		return false, ""
+	}
+	userAgent := r.Header.Get("User-Agent")
+	if ok, reason := d.IsBotUserAgent(userAgent); ok {
+		return true, reason
+	}
+
+	if len(d.CrawlerRanges) > 0 {
+		if ip := d.ClientIP.ClientIP(r); ip.IsValid() {
+			bits := ip.BitLen()
+			if d.ClientIP.Truncate {
+				bits = 48
+				if ip.Is4() {
+					bits = 24
+				}
+			}
+			client := netip.PrefixFrom(ip, bits)
+			for _, p := range d.CrawlerRanges {
+				if p.Overlaps(client) {
+					return true, "ip"
+				}
+			}
+		}
+	}
+
+	if d.Headless {
+		if strings.Contains(strings.ToLower(r.Header.Get("Sec-CH-UA")), "headless") {
+			return true, "headless"
+		}
+	}
+	if d.MissingAcceptLanguage {
+		// Real browsers always send Accept-Language on navigation and fetch requests
+		if strings.HasPrefix(userAgent, "Mozilla/") && r.Header.Get("Accept-Language") == "" {
+			return true, "headless"
+		}
+	}
+
+	return false, ""
+}
+
+// IsBotUserAgent reports whether the User-Agent belongs to a bot, and why.
+func (d *BotDetector) IsBotUserAgent(userAgent string) (bool, string) {
+	if d == nil || userAgent == "" {
+		return false, ""
+	}
+	ua := strings.ToLower(userAgent)
+	if d.Headless {
+		for _, p := range headlessPatterns {
+			if strings.Contains(ua, p) {
+				return true, "headless"
+			}
+		}
+	}
+	for _, p := range d.UserAgentPatterns {
+		if strings.Contains(ua, strings.ToLower(p)) {
+			return true, "useragent"
+		}
+	}
+	if useragent.Parse(userAgent).Bot {
+		return true, "useragent"
+	}
+	return false, ""
+}
+
+// LoadCrawlerRanges reads crawler networks from a local file. The file is
+// either a list of CIDRs, one per line with # comments, or a JSON document
+// in the format published by Google and Bing, ie. googlebot.json.
+func LoadCrawlerRanges(path string) ([]netip.Prefix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("databeat: failed to read crawler ranges: %w", err)
+	}
+
+	var cidrs []string
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var doc struct {
+			Prefixes []struct {
+				IPv4Prefix string `json:"ipv4Prefix"`
+				IPv6Prefix string `json:"ipv6Prefix"`
+			} `json:"prefixes"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("databeat: failed to parse crawler ranges: %w", err)
+		}
+		for _, p := range doc.Prefixes {
+			if p.IPv4Prefix != "" {
+				cidrs = append(cidrs, p.IPv4Prefix)
+			}
+			if p.IPv6Prefix != "" {
+				cidrs = append(cidrs, p.IPv6Prefix)
+			}
+		}
+	} else {
+		scanner := bufio.NewScanner(strings.NewReader(trimmed))
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			if line = strings.TrimSpace(line); line != "" {
+				cidrs = append(cidrs, line)
+			}
+		}
+	}
+
+	return ParseTrustedProxies(cidrs...)
+}
+
+type BotPolicy uint8
+
+const (
+	// BotPolicyTag records bot traffic with device type "bot" and a `_bot` prop.
+	BotPolicyTag BotPolicy = iota
+
+	// BotPolicyDrop drops bot traffic.
+	BotPolicyDrop
+
+	// BotPolicySample records a fraction of bot traffic, tagged.
+	BotPolicySample
+
+	// BotPolicyAllow skips bot detection.
+	BotPolicyAllow
+)
+
+// BotOptions configures bot detection. It is disabled by default, as
+// detected bots have their device type changed to "bot".
+type BotOptions struct {
+	Detector   *BotDetector
+	Policy     BotPolicy
+	SampleRate float64
+}
+
+var DefaultBotOptions = BotOptions{
+	Detector: DefaultBotDetector, Policy: BotPolicyAllow, SampleRate: 0,
+}
+
+// check returns whether a request should be recorded, and the bot reason
+// if the request was made by a bot.
+func (o BotOptions) check(r *http.Request) (bool, string) {
+	if o.Policy == BotPolicyAllow || o.Detector == nil {
+		return true, ""
+	}
+	isBot, reason := o.Detector.IsBot(r)
+	if !isBot {
+		return true, ""
+	}
+	switch o.Policy {
+	case BotPolicyDrop:
+		return false, reason
+	case BotPolicySample:
+		return mrand.Float64() < o.SampleRate, reason
+	default:
+		return true, reason
+	}
+}
+
+func tagBotEvent(ev *Event, reason string) {
+	if ev.Device == nil {
+		ev.Device = &Device{}
+	}
+	ev.Device.Type = "bot"
+	if ev.Props == nil {
+		ev.Props = map[string]string{}
+	}
+	ev.Props["_bot"] = reason
+}
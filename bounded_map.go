@@ -0,0 +1,33 @@
+package databeat
+
+// boundedMap is a map of at most max keys, which evicts the oldest keys
+// first. It is not safe for concurrent use.
+type boundedMap[V any] struct {
+	max    int
+	values map[string]V
+	order  []string // keys by insertion, a ring buffer once full
+	oldest int
+}
+
+func newBoundedMap[V any](max int) *boundedMap[V] {
+	return &boundedMap[V]{max: max, values: map[string]V{}}
+}
+
+func (m *boundedMap[V]) get(key string) (V, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// set sets the value of the key, evicting the oldest key if the map is full.
+func (m *boundedMap[V]) set(key string, v V) {
+	if _, ok := m.values[key]; !ok {
+		if len(m.order) < m.max {
+			m.order = append(m.order, key)
+		} else {
+			delete(m.values, m.order[m.oldest])
+			m.order[m.oldest] = key
+			m.oldest = (m.oldest + 1) % m.max
+		}
+	}
+	m.values[key] = v
+}
@@ -0,0 +1,26 @@
+package databeat
+
+import "testing"
+
+func TestBoundedMap(t *testing.T) {
+	m := newBoundedMap[int](2)
+	m.set("a", 1)
+	m.set("b", 2)
+	m.set("a", 3) // updates do not change the eviction order
+	m.set("c", 4)
+	m.set("d", 5)
+
+	for key, want := range map[string]int{"c": 4, "d": 5} {
+		if v, ok := m.get(key); !ok || v != want {
+			t.Errorf("get(%q) = %d, %v, want %d", key, v, ok, want)
+		}
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, ok := m.get(key); ok {
+			t.Errorf("%q should be evicted", key)
+		}
+	}
+	if len(m.values) != 2 || len(m.order) != 2 {
+		t.Errorf("map should be bounded, got %d values, %d keys", len(m.values), len(m.order))
+	}
+}
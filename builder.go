@@ -0,0 +1,133 @@
+package databeat
+
+import (
+	"maps"
+	"time"
+)
+
+// EventBuilder builds an Event or RawEvent, routing prop values to
+// Event.Props or Event.Nums like Props.ToEventProps.
+//
+//	ev := databeat.NewEvent("LOGIN").
+//		Source("/login").
+//		User(uid).
+//		Prop("method", "email").
+//		Num("retries", 2).
+//		Event()
+type EventBuilder struct {
+	ev  Event
+	app *string
+	ts  *time.Time
+}
+
+func NewEvent(name string) *EventBuilder {
+	return &EventBuilder{ev: Event{Event: name}}
+}
+
+func (b *EventBuilder) Source(source string) *EventBuilder {
+	b.ev.Source = source
+	return b
+}
+
+func (b *EventBuilder) Project(projectID uint64) *EventBuilder {
+	b.ev.ProjectID = projectID
+	return b
+}
+
+func (b *EventBuilder) User(userID string) *EventBuilder {
+	b.ev.UserID = String(userID)
+	return b
+}
+
+func (b *EventBuilder) Session(sessionID string) *EventBuilder {
+	b.ev.SessionID = String(sessionID)
+	return b
+}
+
+func (b *EventBuilder) Device(device *Device) *EventBuilder {
+	b.ev.Device = device
+	return b
+}
+
+func (b *EventBuilder) Country(countryCode string) *EventBuilder {
+	b.ev.CountryCode = String(countryCode)
+	return b
+}
+
+// Prop sets the prop `key` on Event.Props or Event.Nums depending on the
+// type of `v`. Values of other types, such as maps and structs, are kept
+// on Event.Etc, and flattened when tracked if Options.Flatten is enabled.
+func (b *EventBuilder) Prop(key string, v interface{}) *EventBuilder {
+	switch str, num, kind := propValue(v); kind {
+	case propStr:
+		b.setProp(key, str)
+	case propNum:
+		b.Num(key, num)
+	default:
+		if b.ev.Etc == nil {
+			b.ev.Etc = map[string]interface{}{}
+		}
+		b.ev.Etc[key] = v
+	}
+	return b
+}
+
+func (b *EventBuilder) Num(key string, n float64) *EventBuilder {
+	if b.ev.Nums == nil {
+		b.ev.Nums = map[string]float64{}
+	}
+	b.ev.Nums[key] = n
+	return b
+}
+
+// Props sets all props of `props`, see Prop.
+func (b *EventBuilder) Props(props Props) *EventBuilder {
+	for k, v := range props {
+		b.Prop(k, v)
+	}
+	return b
+}
+
+// App sets the app of the RawEvent.
+func (b *EventBuilder) App(app string) *EventBuilder {
+	b.app = String(app)
+	return b
+}
+
+// Time sets the time of the RawEvent, which defaults to the time it is
+// tracked if ClockOptions.Timestamps is set, or else flushed.
+func (b *EventBuilder) Time(ts time.Time) *EventBuilder {
+	ts = ts.UTC()
+	b.ts = &ts
+	return b
+}
+
+func (b *EventBuilder) setProp(key, value string) {
+	if b.ev.Props == nil {
+		b.ev.Props = map[string]string{}
+	}
+	b.ev.Props[key] = value
+}
+
+// Event returns the built event. The builder can be reused, as the event
+// gets copies of its props.
+func (b *EventBuilder) Event() Event {
+	ev := b.ev
+	ev.Props = maps.Clone(b.ev.Props)
+	ev.Nums = maps.Clone(b.ev.Nums)
+	ev.Etc = maps.Clone(b.ev.Etc)
+	if b.ev.Device != nil {
+		device := *b.ev.Device
+		ev.Device = &device
+	}
+	return ev
+}
+
+// RawEvent returns the built event as a raw event. Its TS is nil unless
+// set with Time, so it is set when tracked, see ClockOptions.Timestamps.
+func (b *EventBuilder) RawEvent() RawEvent {
+	ev := b.Event()
+	raw := RawEvent{App: b.app, TS: b.ts}
+	copyEventToRaw(&ev, &raw)
+	return raw
+}
@@ -0,0 +1,254 @@
+package databeat
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/horizon-games/go-databeat/proto"
+)
+
+// ClientHints are the User-Agent Client Hints sent by Chromium based
+// browsers. As the User-Agent string is frozen in these browsers, the hints
+// are the only reliable source for OS version and device model.
+type ClientHints struct {
+	Brand           string
+	BrandVersion    string
+	Platform        string
+	PlatformVersion string
+	Mobile          *bool
+	Model           string
+}
+
+// AcceptClientHintsHeader is the list of high-entropy hints requested from
+// browsers. The low-entropy hints are sent by default.
+const AcceptClientHintsHeader = "Sec-CH-UA, Sec-CH-UA-Mobile, Sec-CH-UA-Platform, Sec-CH-UA-Platform-Version, Sec-CH-UA-Model"
+
+// AcceptClientHints is a middleware which sets the Accept-CH response header,
+// so browsers send the client hints on subsequent requests.
+func AcceptClientHints(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-CH", AcceptClientHintsHeader)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ParseClientHints reads the Sec-CH-UA-* headers. It returns false if the
+// request does not carry any client hints.
+func ParseClientHints(h http.Header) (ClientHints, bool) {
+	var ch ClientHints
+	found := false
+
+	if v := h.Get("Sec-CH-UA"); v != "" {
+		ch.Brand, ch.BrandVersion = parseBrandList(v)
+		found = true
+	}
+	if v := h.Get("Sec-CH-UA-Platform"); v != "" {
+		ch.Platform = unquoteHint(v)
+		found = true
+	}
+	if v := h.Get("Sec-CH-UA-Platform-Version"); v != "" {
+		ch.PlatformVersion = unquoteHint(v)
+		found = true
+	}
+	if v := h.Get("Sec-CH-UA-Mobile"); v != "" {
+		mobile := strings.TrimSpace(v) == "?1"
+		ch.Mobile = &mobile
+		found = true
+	}
+	if v := h.Get("Sec-CH-UA-Model"); v != "" {
+		ch.Model = unquoteHint(v)
+		found = true
+	}
+
+	return ch, found
+}
+
+// Apply merges the client hints into a device parsed from the User-Agent.
+// Hints take precedence as they are not subject to UA string reduction.
+func (ch ClientHints) Apply(device *proto.Device) {
+	if device == nil {
+		return
+	}
+	if ch.Brand != "" && !strings.EqualFold(ch.Brand, device.Browser) {
+		device.Browser = ch.Brand
+		device.BrowserVersion = ch.BrandVersion
+	}
+	if ch.Platform != "" && ch.Platform != "unknown" {
+		device.OS = ch.Platform
+	}
+	if ch.PlatformVersion != "" {
+		device.OSVersion = platformVersion(device.OS, ch.PlatformVersion)
+	}
+	if ch.Mobile != nil && *ch.Mobile {
+		device.Type = "mobile"
+	} else if device.Type == "" && ch.Mobile != nil {
+		device.Type = "desktop"
+	}
+}
+
+// DeviceFromRequest returns the device of the request from its User-Agent
+// merged with its client hints, if any. The device model, which has no
+// field on Device, is returned separately.
+func DeviceFromRequest(r *http.Request) (*proto.Device, string) {
+	if r == nil {
+		return nil, ""
+	}
+	device := DeviceFromUserAgent(r.Header.Get("User-Agent"))
+
+	ch, ok := ParseClientHints(r.Header)
+	if !ok {
+		return device, ""
+	}
+	if device == nil {
+		device = &proto.Device{}
+	}
+	ch.Apply(device)
+
+	return device, ch.Model
+}
+
+// knownBrands maps the brands of client hints to browser names, in order
+// of preference over the generic Chromium brand.
+var knownBrands = map[string]string{
+	"google chrome":    "chrome",
+	"microsoft edge":   "edge",
+	"opera":            "opera",
+	"opera gx":         "opera",
+	"brave":            "brave",
+	"vivaldi":          "vivaldi",
+	"yandex":           "yandex",
+	"samsung internet": "samsung internet",
+}
+
+// parseBrandList picks the most specific brand of a structured header list
+// such as `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`.
+// Known brands are preferred over unknown ones, and those over Chromium.
+// GREASE entries such as "Not;A=Brand" are ignored.
+func parseBrandList(v string) (string, string) {
+	var brand, version string
+	rank := 0
+	for _, item := range parseStructuredList(v) {
+		name := strings.ToLower(item.value)
+		if name == "" || isGreaseBrand(name) {
+			continue
+		}
+
+		r := 2
+		if known, ok := knownBrands[name]; ok {
+			name, r = known, 3
+		} else if name == "chromium" {
+			r = 1
+		}
+		if r > rank {
+			brand, version, rank = name, item.params["v"], r
+		}
+	}
+	return brand, version
+}
+
+// isGreaseBrand reports whether the brand is a GREASE entry, made of the
+// letters "Not A Brand" with arbitrary separators.
+func isGreaseBrand(name string) bool {
+	letters := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, name)
+	return letters == "notabrand"
+}
+
+type structuredItem struct {
+	value  string
+	params map[string]string
+}
+
+// parseStructuredList parses a RFC 8941 list of string or token items with
+// parameters, such as `"Not;A=Brand";v="8", "Chromium";v="117"`.
+func parseStructuredList(v string) []structuredItem {
+	var items []structuredItem
+	for v = strings.TrimLeft(v, " \t"); v != ""; v = strings.TrimLeft(v, " \t") {
+		var item structuredItem
+		item.value, v = parseStructuredValue(v)
+		for v = strings.TrimLeft(v, " "); strings.HasPrefix(v, ";"); v = strings.TrimLeft(v, " ") {
+			v = strings.TrimLeft(v[1:], " ")
+			i := strings.IndexAny(v, "=;,")
+			if i < 0 {
+				i = len(v)
+			}
+			key, value := v[:i], ""
+			v = v[i:]
+			if strings.HasPrefix(v, "=") {
+				value, v = parseStructuredValue(v[1:])
+			}
+			if item.params == nil {
+				item.params = map[string]string{}
+			}
+			item.params[strings.TrimSpace(key)] = value
+		}
+		items = append(items, item)
+
+		// Skip to the next item
+		_, v, _ = strings.Cut(v, ",")
+	}
+	return items
+}
+
+// parseStructuredValue parses a quoted string or a token at the start of v,
+// and returns it with the remainder of v.
+func parseStructuredValue(v string) (string, string) {
+	if !strings.HasPrefix(v, `"`) {
+		i := strings.IndexAny(v, ";,")
+		if i < 0 {
+			i = len(v)
+		}
+		return strings.TrimSpace(v[:i]), v[i:]
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			if i+1 < len(v) {
+				i++
+				b.WriteByte(v[i])
+			}
+		case '"':
+			return b.String(), v[i+1:]
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// platformVersion maps Windows platform versions to the marketing version,
+// see https://learn.microsoft.com/en-us/microsoft-edge/web-platform/how-to-detect-win11
+func platformVersion(os, version string) string {
+	if os != "windows" {
+		return version
+	}
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return version
+	}
+	switch {
+	case n >= 13:
+		return "11"
+	case n > 0:
+		return "10"
+	default:
+		return version
+	}
+}
+
+func unquoteHint(v string) string {
+	v = strings.ToLower(strings.Trim(strings.TrimSpace(v), `"`))
+	switch v {
+	case "chrome os", "chromium os":
+		return "chromeos"
+	}
+	return v
+}
@@ -0,0 +1,25 @@
+package databeat
+
+import "testing"
+
+func TestParseBrandList(t *testing.T) {
+	tests := []struct {
+		header  string
+		brand   string
+		version string
+	}{
+		{`"Google Chrome";v="117", "Not;A=Brand";v="8", "Chromium";v="117"`, "chrome", "117"},
+		{`"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`, "chrome", "124"},
+		{`"Not/A)Brand";v="8", "Chromium";v="126", "Microsoft Edge";v="126"`, "edge", "126"},
+		{`"Not\"A\\Brand";v="99", "Opera";v="110", "Chromium";v="124"`, "opera", "110"},
+		{`"Chromium";v="120", "Not_A Brand";v="8"`, "chromium", "120"},
+		{`"Chromium";v="120";x, "Arc";v="1"`, "arc", "1"},
+		{`"Not A(Brand";v="24"`, "", ""},
+	}
+	for _, tt := range tests {
+		brand, version := parseBrandList(tt.header)
+		if brand != tt.brand || version != tt.version {
+			t.Errorf("parseBrandList(%s) = %q, %q, want %q, %q", tt.header, brand, version, tt.brand, tt.version)
+		}
+	}
+}
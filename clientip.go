@@ -0,0 +1,196 @@
+package databeat
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPResolver determines the IP address of the client which made the
+// request. Forwarding headers (Forwarded, X-Forwarded-For and X-Real-IP) are
+// only honoured when the request arrives from one of the TrustedProxies, and
+// are walked right-to-left until the first untrusted address is found.
+type ClientIPResolver struct {
+	// TrustedProxies are the networks of the proxies and load balancers
+	// which sit in front of the service.
+	TrustedProxies []netip.Prefix
+
+	// Truncate the resolved address to /24 for IPv4 and /48 for IPv6
+	// so the full client address is never used.
+	Truncate bool
+}
+
+var DefaultClientIPResolver = ClientIPResolver{
+	TrustedProxies: nil,
+	Truncate:       true,
+}
+
+// PrivateNetworks are the loopback and private address ranges, which is
+// a convenient set of TrustedProxies for services behind an internal
+// load balancer.
+var PrivateNetworks = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+}
+
+// ParseTrustedProxies parses a list of CIDRs or single IP addresses.
+func ParseTrustedProxies(cidrs ...string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, s := range cidrs {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("databeat: invalid trusted proxy %q: %w", s, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("databeat: invalid trusted proxy %q: %w", s, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ClientIP returns the client address of the request, or the zero Addr
+// if it could not be determined.
+func (c ClientIPResolver) ClientIP(r *http.Request) netip.Addr {
+	if r == nil {
+		return netip.Addr{}
+	}
+	addr := c.clientIP(r)
+	if c.Truncate {
+		addr = TruncateIP(addr)
+	}
+	return addr
+}
+
+func (c ClientIPResolver) clientIP(r *http.Request) netip.Addr {
+	remote := parseIPHost(r.RemoteAddr)
+	if !remote.IsValid() || !c.isTrusted(remote) {
+		return remote
+	}
+
+	var hops []string
+	if h := r.Header.Values("Forwarded"); len(h) > 0 {
+		hops = forwardedFor(h)
+	} else if h := r.Header.Values("X-Forwarded-For"); len(h) > 0 {
+		for _, v := range h {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+	} else if h := r.Header.Get("X-Real-IP"); h != "" {
+		hops = []string{h}
+	}
+
+	// Walk the chain from the closest hop, the first untrusted address
+	// is the client. If the chain is malformed we stop at the last
+	// proxy we know of.
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr := parseIPHost(hops[i])
+		if !addr.IsValid() {
+			break
+		}
+		client = addr
+		if !c.isTrusted(addr) {
+			break
+		}
+	}
+	return client
+}
+
+func (c ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, p := range c.TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIPFromRequest returns the client address of the request using the
+// DefaultClientIPResolver.
+func ClientIPFromRequest(r *http.Request) netip.Addr {
+	return DefaultClientIPResolver.ClientIP(r)
+}
+
+// TruncateIP masks an IPv4 address to /24 and an IPv6 address to /48.
+func TruncateIP(addr netip.Addr) netip.Addr {
+	if !addr.IsValid() {
+		return addr
+	}
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return prefix.Addr()
+}
+
+// forwardedFor returns the for= values of a RFC 7239 Forwarded header.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			found := false
+			for _, pair := range strings.Split(elem, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					hops = append(hops, v)
+					found = true
+					break
+				}
+			}
+			if !found {
+				hops = append(hops, "")
+			}
+		}
+	}
+	return hops
+}
+
+// parseIPHost parses an address which may be quoted, bracketed or have a port.
+func parseIPHost(s string) netip.Addr {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if s == "" {
+		return netip.Addr{}
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap()
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// CountryFromIP returns an extractor which resolves the client address
+// of the request with `resolver` and maps it to a country code with
+// `lookup`, typically backed by a local GeoIP database.
+func CountryFromIP(resolver ClientIPResolver, lookup func(ip netip.Addr) string) CountryExtractor {
+	return CountryExtractorFunc(func(r *http.Request) string {
+		ip := resolver.ClientIP(r)
+		if !ip.IsValid() {
+			return ""
+		}
+		return lookup(ip)
+	})
+}
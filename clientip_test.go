@@ -0,0 +1,162 @@
+package databeat
+
+import (
+	"net/http"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		remote   string
+		headers  map[string]string
+		truncate bool
+		want     string
+	}{
+		{
+			name:    "untrusted remote with spoofed XFF",
+			remote:  "203.0.113.7:1234",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "untrusted remote with spoofed Forwarded",
+			remote:  "203.0.113.7:1234",
+			headers: map[string]string{"Forwarded": "for=1.2.3.4"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "multi-hop trusted chain",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.7, 10.0.0.3, 10.0.0.2"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "chain of trusted proxies only",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			want:    "10.0.0.3",
+		},
+		{
+			name:    "X-Real-IP",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"X-Real-IP": "203.0.113.7"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "Forwarded over X-Forwarded-For",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=203.0.113.7", "X-Forwarded-For": "1.2.3.4"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "RFC 7239 quoted IPv6 with port",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": `for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.2`},
+			want:    "2001:db8:cafe::17",
+		},
+		{
+			name:    "unknown hop stops at the last known proxy",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=unknown, for=10.0.0.2"},
+			want:    "10.0.0.2",
+		},
+		{
+			name:    "obfuscated hop stops at the last known proxy",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=203.0.113.7, for=_hidden"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "hop without for stops at the last known proxy",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=203.0.113.7, proto=https"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:     "IPv4-mapped IPv6 remote with truncation",
+			remote:   "[::ffff:203.0.113.77]:1234",
+			truncate: true,
+			want:     "203.0.113.0",
+		},
+		{
+			name:     "IPv4-mapped IPv6 trusted proxy with truncation",
+			remote:   "[::ffff:10.0.0.1]:1234",
+			headers:  map[string]string{"X-Forwarded-For": "::ffff:203.0.113.77"},
+			truncate: true,
+			want:     "203.0.113.0",
+		},
+		{
+			name:     "IPv6 truncation",
+			remote:   "[2001:db8:cafe:1::17]:1234",
+			truncate: true,
+			want:     "2001:db8:cafe::",
+		},
+		{
+			name:   "invalid remote",
+			remote: "pipe",
+			want:   "invalid IP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			resolver := ClientIPResolver{TrustedProxies: PrivateNetworks, Truncate: tt.truncate}
+			if got := resolver.ClientIP(r).String(); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	got := forwardedFor([]string{`for=192.0.2.60;proto=http;by=203.0.113.43, For="[2001:db8::1]"`, "proto=https"})
+	want := []string{"192.0.2.60", `"[2001:db8::1]"`, ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("forwardedFor = %q, want %q", got, want)
+	}
+}
+
+func TestParseIPHost(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.60":              "192.0.2.60",
+		"192.0.2.60:8080":         "192.0.2.60",
+		`"[2001:db8::1]:4711"`:    "2001:db8::1",
+		"[2001:db8::1]":           "2001:db8::1",
+		"::ffff:192.0.2.60":       "192.0.2.60",
+		"[::ffff:192.0.2.60]:443": "192.0.2.60",
+		"unknown":                 "invalid IP",
+		"_hidden":                 "invalid IP",
+		"":                        "invalid IP",
+	}
+	for in, want := range tests {
+		if got := parseIPHost(in).String(); got != want {
+			t.Errorf("parseIPHost(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies("10.0.0.1", " 192.168.1.7/16", "::ffff:10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("10.0.0.2/32"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTrustedProxies = %v, want %v", got, want)
+	}
+	if _, err := ParseTrustedProxies("proxy"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,126 @@
+package databeat
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/horizon-games/go-databeat/proto"
+)
+
+// ClockOptions configures the client-side timestamps of events.
+type ClockOptions struct {
+	// Timestamps sets the time events are tracked, as unix milliseconds,
+	// on the TimestampProp num of events, and on RawEvent.TS unless set.
+	// Otherwise events are timestamped by the server when flushed, which
+	// may be much later after retries. It is off by default, as it adds a
+	// num to every event.
+	Timestamps bool
+
+	// TimestampProp is the num of events the timestamp is set on.
+	TimestampProp string
+
+	// SkewCorrection corrects the timestamps by the clock skew to the
+	// server, estimated from the Date header of its responses. Timestamps
+	// of events tracked before the first response are not corrected. It
+	// only applies with Timestamps.
+	SkewCorrection bool
+}
+
+var DefaultClockOptions = ClockOptions{
+	Timestamps: false, TimestampProp: "_ts", SkewCorrection: true,
+}
+
+// clock estimates the skew between the local and server clocks.
+type clock struct {
+	mu      sync.Mutex
+	skew    time.Duration
+	samples int
+}
+
+// maxSkewSamples is the weight of the moving average of skew samples.
+const maxSkewSamples = 8
+
+// Now returns the local time corrected by the estimated clock skew.
+func (c *clock) Now() time.Time {
+	return time.Now().UTC().Add(c.Skew())
+}
+
+func (c *clock) Skew() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skew
+}
+
+// observe adds a skew sample from the Date of a response to a request sent
+// at `start` and received at `end`.
+func (c *clock) observe(date time.Time, start, end time.Time) {
+	// Date has a resolution of a second, so the server time is on average
+	// half a second after it, and is compared to the midpoint of the
+	// request to account for latency.
+	server := date.Add(500 * time.Millisecond)
+	local := start.Add(end.Sub(start) / 2)
+	sample := server.Sub(local)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.samples < maxSkewSamples {
+		c.samples++
+	}
+	c.skew += (sample - c.skew) / time.Duration(c.samples)
+}
+
+// clockClient is a HTTP client which observes the clock skew to the server.
+type clockClient struct {
+	client proto.HTTPClient
+	clock  *clock
+}
+
+func (c *clockClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		c.clock.observe(date, start, time.Now())
+	}
+	return resp, nil
+}
+
+// ClockSkew returns the estimated skew of the server clock to the local
+// clock, see ClockOptions.SkewCorrection.
+func (t *Databeat) ClockSkew() time.Duration {
+	return t.clock.Skew()
+}
+
+// stampEvents sets the timestamp of events, see ClockOptions.
+func (t *Databeat) stampEvents(events []*Event) {
+	if !t.options.Clock.Timestamps || t.options.Clock.TimestampProp == "" {
+		return
+	}
+	ts := float64(t.clock.Now().UnixMilli())
+	for _, ev := range events {
+		if _, ok := ev.Nums[t.options.Clock.TimestampProp]; ok {
+			continue
+		}
+		if ev.Nums == nil {
+			ev.Nums = map[string]float64{}
+		}
+		ev.Nums[t.options.Clock.TimestampProp] = ts
+	}
+}
+
+// stampRawEvents sets the timestamp of raw events, see ClockOptions.
+func (t *Databeat) stampRawEvents(events []*RawEvent) {
+	if !t.options.Clock.Timestamps {
+		return
+	}
+	now := t.clock.Now()
+	for _, ev := range events {
+		if ev.TS == nil {
+			ts := now
+			ev.TS = &ts
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package databeat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStampBuilderRawEvent(t *testing.T) {
+	opts := DefaultOptions
+	opts.Clock.Timestamps = true
+	dbeat := newTestClient(t, opts)
+
+	raw := NewEvent("A").App("app").RawEvent()
+	if raw.TS != nil {
+		t.Fatalf("builder should leave TS nil, got %v", raw.TS)
+	}
+	start := time.Now()
+	dbeat.stampRawEvents([]*RawEvent{&raw})
+	if raw.TS == nil || raw.TS.Before(start.Add(-time.Second)) {
+		t.Errorf("TS should be stamped when tracked, got %v", raw.TS)
+	}
+
+	ts := time.Unix(100, 0).UTC()
+	raw = NewEvent("A").Time(ts).RawEvent()
+	dbeat.stampRawEvents([]*RawEvent{&raw})
+	if !raw.TS.Equal(ts) {
+		t.Errorf("TS set with Time should be kept, got %v", raw.TS)
+	}
+}
@@ -0,0 +1,342 @@
+// Command databeat-gen generates Go code and markdown docs for an event
+// catalog from a YAML (or JSON) schema file, so that the catalog is the
+// single source of truth for event names and props.
+//
+//	databeat-gen -schema events.yaml -out events/events.gen.go -pkg events -docs EVENTS.md
+//
+// The schema has the form:
+//
+//	events:
+//	  - name: LOGIN
+//	    description: User logged in.
+//	    owner: growth
+//	    props:
+//	      - name: method
+//	        type: string
+//	        required: true
+//	      - name: retries
+//	        type: number
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Schema struct {
+	Events []*EventSchema `yaml:"events"`
+}
+
+type EventSchema struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	Owner       string        `yaml:"owner"`
+	Props       []*PropSchema `yaml:"props"`
+}
+
+type PropSchema struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+}
+
+func main() {
+	schemaFile := flag.String("schema", "", "path of the schema file (required)")
+	outFile := flag.String("out", "", "path of the generated Go file, defaults to stdout")
+	pkgName := flag.String("pkg", "events", "package name of the generated Go file")
+	docsFile := flag.String("docs", "", "path of the generated markdown docs, optional")
+	flag.Parse()
+
+	if *schemaFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*schemaFile, *outFile, *pkgName, *docsFile); err != nil {
+		fmt.Fprintln(os.Stderr, "databeat-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(schemaFile, outFile, pkgName, docsFile string) error {
+	data, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return err
+	}
+
+	var schema Schema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", schemaFile, err)
+	}
+	if err := schema.validate(); err != nil {
+		return fmt.Errorf("invalid schema %s: %w", schemaFile, err)
+	}
+
+	code, err := render(goTemplate, map[string]any{"Package": pkgName, "Schema": &schema, "Source": schemaFile})
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(code)
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+	if outFile == "" {
+		os.Stdout.Write(formatted)
+	} else if err := os.WriteFile(outFile, formatted, 0644); err != nil {
+		return err
+	}
+
+	if docsFile != "" {
+		docs, err := render(docsTemplate, map[string]any{"Schema": &schema, "Source": schemaFile})
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(docsFile, docs, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validate() error {
+	if len(s.Events) == 0 {
+		return fmt.Errorf("no events defined")
+	}
+	events := map[string]bool{}
+	// Identifiers declared by the generated code, and the event declaring them
+	idents := map[string]string{"EventTypesMap": ""}
+	for _, ev := range s.Events {
+		if ev.Name == "" {
+			return fmt.Errorf("event without a name")
+		}
+		if events[ev.Name] {
+			return fmt.Errorf("duplicate event %q", ev.Name)
+		}
+		events[ev.Name] = true
+		for _, ident := range []string{"Event" + ev.GoName(), ev.GoName() + "Props", "New" + ev.GoName()} {
+			other, ok := idents[ident]
+			if ok && other == "" {
+				return fmt.Errorf("event %q: generated name %s is reserved", ev.Name, ident)
+			} else if ok {
+				return fmt.Errorf("events %q and %q both generate %s", other, ev.Name, ident)
+			}
+			idents[ident] = ev.Name
+		}
+
+		props := map[string]bool{}
+		propGoNames := map[string]string{}
+		for _, p := range ev.Props {
+			if p.Name == "" {
+				return fmt.Errorf("event %q: prop without a name", ev.Name)
+			}
+			if props[p.Name] {
+				return fmt.Errorf("event %q: duplicate prop %q", ev.Name, p.Name)
+			}
+			props[p.Name] = true
+			if other, ok := propGoNames[p.GoName()]; ok {
+				return fmt.Errorf("event %q: props %q and %q have the same Go name %s", ev.Name, other, p.Name, p.GoName())
+			}
+			propGoNames[p.GoName()] = p.Name
+
+			switch p.Type {
+			case "":
+				p.Type = "string"
+			case "string", "number", "bool":
+			default:
+				return fmt.Errorf("event %q: prop %q has invalid type %q, must be string, number or bool", ev.Name, p.Name, p.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// UsesStrconv reports whether the generated code formats required bools.
+func (s *Schema) UsesStrconv() bool {
+	for _, ev := range s.Events {
+		for _, p := range ev.Props {
+			if p.Required && p.Type == "bool" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ev *EventSchema) GoName() string {
+	return goName(ev.Name)
+}
+
+func (p *PropSchema) GoName() string {
+	return goName(p.Name)
+}
+
+func (p *PropSchema) GoType() string {
+	switch p.Type {
+	case "number":
+		return "float64"
+	case "bool":
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+// goName converts names such as "checkout.add_to_cart" to "CheckoutAddToCart".
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		} else if b.Len() > 0 && isUpperName(name) {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "X" + s
+	}
+	return s
+}
+
+func isUpperName(name string) bool {
+	return strings.ToUpper(name) == name
+}
+
+func render(tmpl *template.Template, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var funcs = template.FuncMap{
+	"comment": func(s string) string {
+		return strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n// ")
+	},
+	"cell": func(s string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(s), "\n", " "), "|", `\|`)
+	},
+}
+
+var goTemplate = template.Must(template.New("go").Funcs(funcs).Parse(`// Code generated by databeat-gen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .Schema.UsesStrconv}}
+	"strconv"
+{{end}}
+	databeat "github.com/horizon-games/go-databeat"
+)
+
+// Event names
+const (
+{{- range .Schema.Events}}
+	{{- if .Description}}
+	// Event{{.GoName}}: {{comment .Description}}
+	{{- end}}
+	Event{{.GoName}} = {{printf "%q" .Name}}
+{{- end}}
+)
+
+// EventTypesMap is the catalog of events, for use with databeat.EventTypes.
+var EventTypesMap = map[string]uint32{
+{{- range $i, $ev := .Schema.Events}}
+	Event{{$ev.GoName}}: {{$i}},
+{{- end}}
+}
+{{range .Schema.Events}}
+// {{.GoName}}Props are the props of the {{.Name}} event.
+type {{.GoName}}Props struct {
+{{- range .Props}}
+	{{- if .Description}}
+	// {{comment .Description}}
+	{{- end}}
+	{{.GoName}} {{.GoType}}{{if not .Required}} // optional{{end}}
+{{- end}}
+}
+
+// New{{.GoName}} returns the {{.Name}} event.{{if .Description}} {{comment .Description}}{{end}}
+{{- if .Owner}}
+// Owner: {{.Owner}}
+{{- end}}
+func New{{.GoName}}(p {{.GoName}}Props) databeat.Event {
+	ev := databeat.Event{
+		Event: Event{{.GoName}},
+		Props: map[string]string{},
+		Nums:  map[string]float64{},
+	}
+{{- range .Props}}
+	{{- if .Required}}
+	{{- if eq .Type "number"}}
+	ev.Nums[{{printf "%q" .Name}}] = p.{{.GoName}}
+	{{- else if eq .Type "bool"}}
+	ev.Props[{{printf "%q" .Name}}] = strconv.FormatBool(p.{{.GoName}})
+	{{- else}}
+	ev.Props[{{printf "%q" .Name}}] = p.{{.GoName}}
+	{{- end}}
+	{{- else}}
+	{{- if eq .Type "number"}}
+	if p.{{.GoName}} != 0 {
+		ev.Nums[{{printf "%q" .Name}}] = p.{{.GoName}}
+	}
+	{{- else if eq .Type "bool"}}
+	if p.{{.GoName}} {
+		ev.Props[{{printf "%q" .Name}}] = "true"
+	}
+	{{- else}}
+	if p.{{.GoName}} != "" {
+		ev.Props[{{printf "%q" .Name}}] = p.{{.GoName}}
+	}
+	{{- end}}
+	{{- end}}
+{{- end}}
+	return ev
+}
+{{end}}`))
+
+var docsTemplate = template.Must(template.New("docs").Funcs(funcs).Parse(`# Event catalog
+
+_Generated by databeat-gen from {{.Source}}. Do not edit._
+
+| Event | Owner | Description |
+| --- | --- | --- |
+{{- range .Schema.Events}}
+| ` + "`{{.Name}}`" + ` | {{cell .Owner}} | {{cell .Description}} |
+{{- end}}
+{{range .Schema.Events}}
+## {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}
+{{- if .Owner}}
+Owner: {{.Owner}}
+{{end}}
+{{- if .Props}}
+| Prop | Type | Required | Description |
+| --- | --- | --- | --- |
+{{- range .Props}}
+| ` + "`{{.Name}}`" + ` | {{.Type}} | {{if .Required}}yes{{else}}no{{end}} | {{cell .Description}} |
+{{- end}}
+{{else}}
+No props.
+{{end}}
+{{- end}}`))
@@ -0,0 +1,39 @@
+package databeat
+
+import "context"
+
+type contextKey struct{ name string }
+
+var fromContextKey = &contextKey{"from"}
+
+// NewContext returns a copy of ctx carrying the tracking identity `from`.
+// If ctx already carries an identity, the non-zero fields of `from` are
+// merged over it, so middlewares can each attach part of the identity.
+func NewContext(ctx context.Context, from From) context.Context {
+	if parent, ok := FromContext(ctx); ok {
+		from = mergeFrom(parent, from)
+	}
+	return context.WithValue(ctx, fromContextKey, from)
+}
+
+// FromContext returns the tracking identity carried by ctx, if any.
+func FromContext(ctx context.Context) (From, bool) {
+	if ctx == nil {
+		return From{}, false
+	}
+	from, ok := ctx.Value(fromContextKey).(From)
+	return from, ok
+}
+
+// TrackCtx tracks the events with the identity carried by ctx, see NewContext.
+func (t *Databeat) TrackCtx(ctx context.Context, events ...Event) {
+	from, _ := FromContext(ctx)
+	t.TrackEvent(from, events...)
+}
+
+// TrackCtx tracks the events with the identity carried by ctx merged over
+// the origin of the scope.
+func (s *Scope) TrackCtx(ctx context.Context, events ...Event) {
+	from, _ := FromContext(ctx)
+	s.TrackEvent(from, events...)
+}
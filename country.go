@@ -0,0 +1,117 @@
+package databeat
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CountryExtractor returns the ISO-3166 alpha-2 country code of the request,
+// or an empty string if it is unable to determine one.
+type CountryExtractor interface {
+	CountryCode(r *http.Request) string
+}
+
+// CountryExtractorFunc is an adapter to allow the use of ordinary functions
+// as a CountryExtractor.
+type CountryExtractorFunc func(r *http.Request) string
+
+func (f CountryExtractorFunc) CountryCode(r *http.Request) string {
+	return f(r)
+}
+
+// CountryHeader returns an extractor which reads the country code from
+// the header `name`. Values such as "US,mountain view" (GCP load balancer
+// custom headers) are supported by taking the part before the first comma.
+func CountryHeader(name string) CountryExtractor {
+	return CountryExtractorFunc(func(r *http.Request) string {
+		h := r.Header.Get(name)
+		if h == "" {
+			return ""
+		}
+		if i := strings.IndexByte(h, ','); i >= 0 {
+			h = h[:i]
+		}
+		return strings.TrimSpace(h)
+	})
+}
+
+var (
+	CloudflareCountry = CountryHeader("CF-IPCountry")
+	CloudFrontCountry = CountryHeader("CloudFront-Viewer-Country")
+	FastlyCountry     = CountryHeader("Fastly-Geo-Country-Code")
+	VercelCountry     = CountryHeader("X-Vercel-IP-Country")
+	GCPCountry        = CountryHeader("X-Client-Geo-Location")
+	AppEngineCountry  = CountryHeader("X-AppEngine-Country")
+)
+
+// CountryResolver is an ordered chain of country extractors. The first
+// extractor to return a valid ISO-3166 alpha-2 code wins.
+type CountryResolver []CountryExtractor
+
+var DefaultCountryResolver = CountryResolver{
+	CloudflareCountry,
+	CloudFrontCountry,
+	FastlyCountry,
+	VercelCountry,
+	GCPCountry,
+	AppEngineCountry,
+}
+
+func (c CountryResolver) CountryCode(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	for _, ex := range c {
+		code := strings.ToUpper(ex.CountryCode(r))
+		if IsCountryCode(code) {
+			return code
+		}
+	}
+	return ""
+}
+
+// IsCountryCode reports whether code is an assigned ISO-3166 alpha-2 code.
+// Codes must be upper-case. Placeholder values used by CDNs such as "XX"
+// (unknown) or "T1" (Tor) are not valid.
+func IsCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	_, ok := iso3166Alpha2[code]
+	return ok
+}
+
+var iso3166Alpha2 = func() map[string]struct{} {
+	codes := strings.Fields(`
+		AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ
+		BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+		CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ
+		DE DJ DK DM DO DZ
+		EC EE EG EH ER ES ET
+		FI FJ FK FM FO FR
+		GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY
+		HK HM HN HR HT HU
+		ID IE IL IM IN IO IQ IR IS IT
+		JE JM JO JP
+		KE KG KH KI KM KN KP KR KW KY KZ
+		LA LB LC LI LK LR LS LT LU LV LY
+		MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ
+		NA NC NE NF NG NI NL NO NP NR NU NZ
+		OM
+		PA PE PF PG PH PK PL PM PN PR PS PT PW PY
+		QA
+		RE RO RS RU RW
+		SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ
+		TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ
+		UA UG UM US UY UZ
+		VA VC VE VG VI VN VU
+		WF WS
+		YE YT
+		ZA ZM ZW
+	`)
+	m := make(map[string]struct{}, len(codes))
+	for _, c := range codes {
+		m[c] = struct{}{}
+	}
+	return m
+}()
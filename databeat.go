@@ -22,10 +22,18 @@ type Databeat struct {
 	authKey string
 	authCtx context.Context
 
-	assertTypes map[string]struct{}
+	registry    *Registry
+	firstTouch  *firstTouchStore
+	globalProps map[string]string
+	alerts      *alerter
+	clock       *clock
+	sequencer   *sequencer
 	queue       []*proto.Event
 	queueRaw    []*proto.RawEvent
 	flushSem    chan struct{}
+	flushMu     sync.Mutex
+
+	warnedRawView atomic.Bool
 
 	stats stats
 
@@ -45,6 +53,61 @@ type Options struct {
 	MaxQueueSize        int
 	SetServerClientProp bool
 	HTTPClient          *http.Client
+
+	// Registry is the event type registry used to assert event types of
+	// Track and TrackRaw, and which can be updated at runtime. If nil, it
+	// is created from AssertEventTypes.
+	Registry *Registry
+
+	// CountryResolver is the ordered chain of extractors used to determine
+	// the country of a user request. Prepend or append your own extractors
+	// to DefaultCountryResolver to support other CDNs or load balancers.
+	CountryResolver CountryResolver
+
+	// ClientIP resolves the client address of user requests. The address
+	// is never stored on events.
+	ClientIP ClientIPResolver
+
+	// Bots is the bot detection policy applied to user requests.
+	Bots BotOptions
+
+	// Attribution adds referrer and UTM parameters of user requests to events.
+	Attribution AttributionOptions
+
+	// Locale adds the locale and timezone of user requests to events.
+	Locale LocaleOptions
+
+	// Source configures how the source of user requests is derived from
+	// their route pattern or path.
+	Source SourceOptions
+
+	// Flatten configures how nested values, ie. of Event.Etc, are
+	// flattened into props with dotted keys. Event.Etc is only flattened
+	// and sent if enabled.
+	Flatten FlattenOptions
+
+	// GlobalContext adds static props such as service, version and host
+	// to every event.
+	GlobalContext GlobalContextOptions
+
+	// Validation validates the props of events against schemas, before
+	// they are enriched.
+	Validation ValidationOptions
+
+	// Alerts are callbacks for invalid, dropped and failed events.
+	Alerts AlertOptions
+
+	// Clock sets client-side timestamps on events, corrected by the clock
+	// skew to the server.
+	Clock ClockOptions
+
+	// Sequence sets sequence numbers on events, and configures their
+	// ordered delivery.
+	Sequence SequenceOptions
+
+	// Enrichers is the ordered chain of enrichers applied to events before
+	// they are queued. Defaults to DefaultEnrichers.
+	Enrichers []Enricher
 }
 
 var DefaultOptions = Options{
@@ -56,6 +119,19 @@ var DefaultOptions = Options{
 	FlushConcurrency:    10,
 	MaxQueueSize:        10_000,
 	SetServerClientProp: false,
+	CountryResolver:     DefaultCountryResolver,
+	ClientIP:            DefaultClientIPResolver,
+	Bots:                DefaultBotOptions,
+	Attribution:         DefaultAttributionOptions,
+	Locale:              DefaultLocaleOptions,
+	Source:              DefaultSourceOptions,
+	Flatten:             DefaultFlattenOptions,
+	GlobalContext:       DefaultGlobalContextOptions,
+	Validation:          DefaultValidationOptions,
+	Alerts:              DefaultAlertOptions,
+	Clock:               DefaultClockOptions,
+	Sequence:            DefaultSequenceOptions,
+	Enrichers:           DefaultEnrichers,
 	HTTPClient: &http.Client{
 		Timeout: 60 * time.Second,
 		Transport: &http.Transport{
@@ -110,13 +186,24 @@ func NewDatabeatClient(host, authKey string, logger *slog.Logger, opts ...Option
 	if options.FlushConcurrency <= 0 {
 		options.FlushConcurrency = 1
 	}
+	if options.CountryResolver == nil {
+		options.CountryResolver = DefaultCountryResolver
+	}
+	if options.Enrichers == nil {
+		options.Enrichers = DefaultEnrichers
+	}
 
-	assertTypes := map[string]struct{}{}
-	for _, et := range options.AssertEventTypes {
-		assertTypes[et] = struct{}{}
+	registry := options.Registry
+	if registry == nil {
+		registry = NewRegistry(options.AssertEventTypes...)
 	}
 
-	client := proto.NewDatabeatClient(host, options.HTTPClient)
+	clock := &clock{}
+	var httpClient proto.HTTPClient = options.HTTPClient
+	if options.Clock.Timestamps && options.Clock.SkewCorrection {
+		httpClient = &clockClient{client: options.HTTPClient, clock: clock}
+	}
+	client := proto.NewDatabeatClient(host, httpClient)
 
 	headers := http.Header{}
 	headers.Set("Authorization", fmt.Sprintf("BEARER %s", authKey))
@@ -132,7 +219,12 @@ func NewDatabeatClient(host, authKey string, logger *slog.Logger, opts ...Option
 		Enabled:     true,
 		authKey:     authKey,
 		authCtx:     authCtx,
-		assertTypes: assertTypes,
+		registry:    registry,
+		firstTouch:  newFirstTouchStore(options.Attribution.MaxSessions),
+		globalProps: GlobalContextProps(options.GlobalContext),
+		alerts:      newAlerter(options.Alerts),
+		clock:       clock,
+		sequencer:   newSequencer(options.Sequence.MaxSessions),
 		queue:       make([]*proto.Event, 0, options.MaxQueueSize),
 		queueRaw:    make([]*proto.RawEvent, 0, options.MaxQueueSize),
 		flushSem:    make(chan struct{}, options.FlushConcurrency),
@@ -189,54 +281,11 @@ func (t *Databeat) TrackEvent(from From, trackEvents ...Event) {
 		events[i] = &v
 	}
 
-	// Set event based on http request and user info
-	var uid string
-	var ident Ident
-	if from.UserHTTPRequest != nil || from.UserID != "" {
-		uid, ident = GenUserIDFromRequest(from.UserHTTPRequest, from.UserID, t.options.Privacy)
-	}
-
-	// Set ident to service if no user details are passed, and project id is passed
-	if uid == "" && from.ProjectID > 0 {
-		ident = IDENT_SERVICE
-	}
-
-	for _, ev := range events {
-		// User & ident
-		if ev.UserID == nil || *ev.UserID == "" {
-			uidCopy := uid
-			ev.UserID = &uidCopy
-			ev.Ident = uint8(ident)
-		}
-
-		// Decorate event if project id is passed
-		if from.ProjectID > 0 {
-			ev.ProjectID = from.ProjectID
-		}
-
-		// Decorate event if user request is passed
-		if from.UserHTTPRequest != nil {
-			// Source
-			if ev.Source == "" {
-				ev.Source = from.UserHTTPRequest.URL.Path
-			}
-
-			// Device from User-Agent
-			userAgent := from.UserHTTPRequest.Header.Get("User-Agent")
-			if userAgent != "" {
-				ev.Device = DeviceFromUserAgent(userAgent)
-			}
-
-			// Country
-			countryCode := CountryCodeFromRequest(from.UserHTTPRequest)
-			if countryCode != "" {
-				ev.CountryCode = &countryCode
-			}
-		}
-	}
+	// Validate and decorate events based on http request and user info
+	events = t.enrich(from, t.validate(events, nil))
 
 	// Track!
-	t.Track(events...)
+	t.track(events)
 }
 
 // TrackUserEvent will track the event associated to a particular user. We use the http request
@@ -250,46 +299,40 @@ func (t *Databeat) TrackUserEvent(r *http.Request, userID string, userEvents ...
 }
 
 // Track is a low-level track function where you control the full payload.
-// The method TrackUserEvent calls Track as well.
+// Only the enrichers which do not depend on a user request are applied.
 func (t *Databeat) Track(events ...*Event) {
 	if !t.Enabled {
 		return
 	}
+	t.track(t.enrich(From{}, t.validate(events, nil)))
+}
 
-	if !t.IsRunning() {
-		t.log.Warn("databeat worker is not running, skipping event.")
+func (t *Databeat) track(events []*Event) {
+	if len(events) == 0 {
 		return
 	}
 
-	// Validate event types at runtime if EventTypes has been provided in options
-	if len(t.assertTypes) > 0 {
-		var valid bool
-		var invalidNames []string
-		valid, invalidNames, events = validateEventTypes(t.assertTypes, events)
-		if !valid {
-			t.log.Warn(fmt.Sprintf("databeat: %d invalid event types", len(invalidNames)), slog.Any("invalidEvents", invalidNames))
-			// TODO: add alerter here
-		}
-	}
-
-	// Annotate events
-	for _, ev := range events {
-		if ev.Props == nil {
-			ev.Props = map[string]string{}
-		}
-		ev.Props["_tracker"] = "go-databeat"
+	if !t.IsRunning() {
+		t.log.Warn("databeat worker is not running, skipping event.")
+		t.alerts.drop(ReasonNotRunning, len(events), eventNames(events))
+		return
 	}
 
 	// Update stats
 	t.stats.NumEvents.Add(uint64(len(events)))
 
+	t.stampEvents(events)
+
 	// Add events to the queue
 	t.mu.Lock()
 
+	t.sequenceEvents(events)
+
 	// Check for queue overflow
 	if len(events) >= t.options.MaxQueueSize {
 		dropCount := len(t.queue) + len(events) - t.options.MaxQueueSize
 		t.log.Warn("databeat: queue overflow, dropping events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonQueueOverflow, dropCount, eventNames(events))
 		t.queue = t.queue[:0]
 		events = events[len(events)-t.options.MaxQueueSize:]
 	} else if totalSize := len(t.queue) + len(events); totalSize > t.options.MaxQueueSize {
@@ -298,6 +341,7 @@ func (t *Databeat) Track(events ...*Event) {
 			dropCount = len(t.queue)
 		}
 		t.log.Warn("databeat: queue overflow, dropping events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonQueueOverflow, dropCount, eventNames(t.queue[:dropCount]))
 		t.queue = t.queue[dropCount:]
 	}
 
@@ -314,21 +358,52 @@ func (t *Databeat) TrackRaw(events ...*RawEvent) {
 	if !t.Enabled {
 		return
 	}
+	t.trackRaw(t.enrichRaw(From{}, t.validateRaw(events, nil)))
+}
+
+// TrackRawEvent tracks raw events with the enrichment of TrackEvent, ie.
+// the user, device and country details of `from` are set on the raw
+// events, so services with raw event permissions get the same decoration.
+func (t *Databeat) TrackRawEvent(from From, trackEvents ...RawEvent) {
+	if !t.Enabled {
+		return
+	}
+
+	// Copy events
+	events := make([]*RawEvent, len(trackEvents))
+	for i, ev := range trackEvents {
+		v := ev // copy
+		events[i] = &v
+	}
+
+	t.trackRaw(t.enrichRaw(from, t.validateRaw(events, nil)))
+}
+
+func (t *Databeat) trackRaw(events []*RawEvent) {
+	if len(events) == 0 {
+		return
+	}
 
 	if !t.IsRunning() {
 		t.log.Warn("databeat worker is not running, skipping event.")
+		t.alerts.drop(ReasonNotRunning, len(events), rawEventNames(events))
 		return
 	}
 
 	// Update stats
 	t.stats.NumEvents.Add(uint64(len(events)))
 
+	t.stampRawEvents(events)
+
 	t.mu.Lock()
 
+	t.sequenceRawEvents(events)
+
 	// Check for queue overflow
 	if len(events) >= t.options.MaxQueueSize {
 		dropCount := len(t.queueRaw) + len(events) - t.options.MaxQueueSize
 		t.log.Warn("databeat: queue overflow, dropping raw events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonQueueOverflow, dropCount, rawEventNames(events))
 		t.queueRaw = t.queueRaw[:0]
 		events = events[len(events)-t.options.MaxQueueSize:]
 	} else if totalSize := len(t.queueRaw) + len(events); totalSize > t.options.MaxQueueSize {
@@ -337,6 +412,7 @@ func (t *Databeat) TrackRaw(events ...*RawEvent) {
 			dropCount = len(t.queueRaw)
 		}
 		t.log.Warn("databeat: queue overflow, dropping raw events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonQueueOverflow, dropCount, rawEventNames(t.queueRaw[:dropCount]))
 		t.queueRaw = t.queueRaw[dropCount:]
 	}
 
@@ -367,6 +443,12 @@ func (t *Databeat) Flush(ctx context.Context) error {
 		}
 	}
 
+	// Flush one at a time, so batches are delivered in order
+	if t.options.Sequence.OrderedDelivery {
+		t.flushMu.Lock()
+		defer t.flushMu.Unlock()
+	}
+
 	// copy queue
 	t.mu.Lock()
 
@@ -402,59 +484,33 @@ func (t *Databeat) Flush(ctx context.Context) error {
 		var wg sync.WaitGroup
 
 		for i := 0; i < len(trackBatch); i += t.options.FlushBatchSize {
-			wg.Add(1)
-
 			events := trackBatch[i:min(i+t.options.FlushBatchSize, len(trackBatch))]
 			if t.options.SetServerClientProp {
 				updateEventClientProp(events)
 			}
 			updateEventDeviceType(events, ServerDevice())
 
+			// Send batches one at a time, and re-queue the failed batch
+			// with the following ones ahead of newer events
+			if t.options.Sequence.OrderedDelivery {
+				if !t.sendBatch(flushCtx, events) {
+					t.requeue(trackBatch[i:])
+					break
+				}
+				flushedBatch.Add(uint32(len(events)))
+				continue
+			}
+
+			wg.Add(1)
 			t.flushSem <- struct{}{}
 			go func(events []*proto.Event) {
 				defer func() { <-t.flushSem }()
 				defer wg.Done()
 
-				var backoff time.Duration = initialBackoff
-				for attempt := 0; attempt < maxRetries; attempt++ {
-					if flushCtx.Err() != nil {
-						t.requeue(events)
-						return
-					}
-
-					reqCtx, cancel := context.WithTimeout(flushCtx, t.options.FlushTimeout)
-
-					ok, err := t.Client.Tick(reqCtx, events)
-					cancel()
-
-					if err == nil && ok {
-						flushedBatch.Add(uint32(len(events)))
-						return
-					}
-
-					if flushCtx.Err() != nil {
-						t.requeue(events)
-						return
-					}
-
-					if attempt < maxRetries-1 {
-						t.log.Warn("databeat: Tick failed, retrying",
-							slog.Int("attempt", attempt+1),
-							slog.Int("events", len(events)),
-							slog.Any("err", err))
-						if !waitBackoff(flushCtx, backoff) {
-							t.requeue(events)
-							return
-						}
-						backoff = min(backoff*2, maxBackoff)
-					} else {
-						t.log.Error("databeat: Tick failed after retries, re-queueing events",
-							slog.Int("attempt", maxRetries),
-							slog.Int("events", len(events)),
-							slog.Any("err", err))
-						t.stats.NumFails.Add(uint64(len(events)))
-						t.requeue(events)
-					}
+				if t.sendBatch(flushCtx, events) {
+					flushedBatch.Add(uint32(len(events)))
+				} else {
+					t.requeue(events)
 				}
 			}(events)
 		}
@@ -467,55 +523,28 @@ func (t *Databeat) Flush(ctx context.Context) error {
 		var wg sync.WaitGroup
 
 		for i := 0; i < len(rawBatch); i += t.options.FlushBatchSize {
-			wg.Add(1)
 			events := rawBatch[i:min(i+t.options.FlushBatchSize, len(rawBatch))]
 			updateRawEventDeviceType(events, ServerDevice())
 
+			if t.options.Sequence.OrderedDelivery {
+				if !t.sendRawBatch(flushCtx, events) {
+					t.requeueRaw(rawBatch[i:])
+					break
+				}
+				flushedRaw.Add(uint32(len(events)))
+				continue
+			}
+
+			wg.Add(1)
 			t.flushSem <- struct{}{}
 			go func(events []*proto.RawEvent) {
 				defer func() { <-t.flushSem }()
 				defer wg.Done()
 
-				var backoff time.Duration = initialBackoff
-				for attempt := 0; attempt < maxRetries; attempt++ {
-					if flushCtx.Err() != nil {
-						t.requeueRaw(events)
-						return
-					}
-
-					reqCtx, cancel := context.WithTimeout(flushCtx, t.options.FlushTimeout)
-
-					ok, err := t.Client.RawEvents(reqCtx, events)
-					cancel()
-
-					if err == nil && ok {
-						flushedRaw.Add(uint32(len(events)))
-						return
-					}
-
-					if flushCtx.Err() != nil {
-						t.requeueRaw(events)
-						return
-					}
-
-					if attempt < maxRetries-1 {
-						t.log.Warn("databeat: RawEvents failed, retrying",
-							slog.Int("attempt", attempt+1),
-							slog.Int("events", len(events)),
-							slog.Any("err", err))
-						if !waitBackoff(flushCtx, backoff) {
-							t.requeueRaw(events)
-							return
-						}
-						backoff = min(backoff*2, maxBackoff)
-					} else {
-						t.log.Error("databeat: RawEvents failed after retries, re-queueing events",
-							slog.Int("attempt", maxRetries),
-							slog.Int("events", len(events)),
-							slog.Any("err", err))
-						t.stats.NumFails.Add(uint64(len(events)))
-						t.requeueRaw(events)
-					}
+				if t.sendRawBatch(flushCtx, events) {
+					flushedRaw.Add(uint32(len(events)))
+				} else {
+					t.requeueRaw(events)
 				}
 			}(events)
 		}
@@ -528,6 +557,92 @@ func (t *Databeat) Flush(ctx context.Context) error {
 	return nil
 }
 
+// sendBatch sends events to the Tick endpoint with retries, and returns
+// false if they should be re-queued.
+func (t *Databeat) sendBatch(flushCtx context.Context, events []*proto.Event) bool {
+	var backoff time.Duration = initialBackoff
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if flushCtx.Err() != nil {
+			return false
+		}
+
+		reqCtx, cancel := context.WithTimeout(flushCtx, t.options.FlushTimeout)
+
+		ok, err := t.Client.Tick(reqCtx, events)
+		cancel()
+
+		if err == nil && ok {
+			return true
+		}
+
+		if flushCtx.Err() != nil {
+			return false
+		}
+
+		if attempt < maxRetries-1 {
+			t.log.Warn("databeat: Tick failed, retrying",
+				slog.Int("attempt", attempt+1),
+				slog.Int("events", len(events)),
+				slog.Any("err", err))
+			if !waitBackoff(flushCtx, backoff) {
+				return false
+			}
+			backoff = min(backoff*2, maxBackoff)
+		} else {
+			t.log.Error("databeat: Tick failed after retries, re-queueing events",
+				slog.Int("attempt", maxRetries),
+				slog.Int("events", len(events)),
+				slog.Any("err", err))
+			t.stats.NumFails.Add(uint64(len(events)))
+			t.alerts.flushError(ReasonTick, len(events), eventNames(events), err)
+		}
+	}
+	return false
+}
+
+// sendRawBatch sends events to the RawEvents endpoint with retries, and
+// returns false if they should be re-queued.
+func (t *Databeat) sendRawBatch(flushCtx context.Context, events []*proto.RawEvent) bool {
+	var backoff time.Duration = initialBackoff
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if flushCtx.Err() != nil {
+			return false
+		}
+
+		reqCtx, cancel := context.WithTimeout(flushCtx, t.options.FlushTimeout)
+
+		ok, err := t.Client.RawEvents(reqCtx, events)
+		cancel()
+
+		if err == nil && ok {
+			return true
+		}
+
+		if flushCtx.Err() != nil {
+			return false
+		}
+
+		if attempt < maxRetries-1 {
+			t.log.Warn("databeat: RawEvents failed, retrying",
+				slog.Int("attempt", attempt+1),
+				slog.Int("events", len(events)),
+				slog.Any("err", err))
+			if !waitBackoff(flushCtx, backoff) {
+				return false
+			}
+			backoff = min(backoff*2, maxBackoff)
+		} else {
+			t.log.Error("databeat: RawEvents failed after retries, re-queueing events",
+				slog.Int("attempt", maxRetries),
+				slog.Int("events", len(events)),
+				slog.Any("err", err))
+			t.stats.NumFails.Add(uint64(len(events)))
+			t.alerts.flushError(ReasonRawEvents, len(events), rawEventNames(events), err)
+		}
+	}
+	return false
+}
+
 func (t *Databeat) Reset() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -536,14 +651,27 @@ func (t *Databeat) Reset() {
 	t.queueRaw = t.queueRaw[:0]
 }
 
-// requeue adds events back to the queue with overflow protection.
+// requeue adds events back to the queue with overflow protection. With
+// ordered delivery, they are added ahead of newer events.
 func (t *Databeat) requeue(events []*proto.Event) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	if t.options.Sequence.OrderedDelivery {
+		queue := append(events[:len(events):len(events)], t.queue...)
+		if dropCount := len(queue) - t.options.MaxQueueSize; dropCount > 0 {
+			t.log.Warn("databeat: re-queue overflow, dropping oldest events", slog.Int("dropped", dropCount))
+			t.alerts.drop(ReasonRequeueOverflow, dropCount, eventNames(queue[:dropCount]))
+			queue = queue[dropCount:]
+		}
+		t.queue = append(t.queue[:0], queue...)
+		return
+	}
+
 	if len(events) >= t.options.MaxQueueSize {
 		dropCount := len(t.queue) + len(events) - t.options.MaxQueueSize
 		t.log.Warn("databeat: re-queue overflow, dropping events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonRequeueOverflow, dropCount, eventNames(events))
 		t.queue = t.queue[:0]
 		events = events[len(events)-t.options.MaxQueueSize:]
 	} else if totalSize := len(t.queue) + len(events); totalSize > t.options.MaxQueueSize {
@@ -552,19 +680,33 @@ func (t *Databeat) requeue(events []*proto.Event) {
 			dropCount = len(t.queue)
 		}
 		t.log.Warn("databeat: re-queue overflow, dropping oldest events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonRequeueOverflow, dropCount, eventNames(t.queue[:dropCount]))
 		t.queue = t.queue[dropCount:]
 	}
 	t.queue = append(t.queue, events...)
 }
 
 // requeueRaw adds raw events back to the queue with overflow protection.
+// With ordered delivery, they are added ahead of newer events.
 func (t *Databeat) requeueRaw(events []*proto.RawEvent) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	if t.options.Sequence.OrderedDelivery {
+		queue := append(events[:len(events):len(events)], t.queueRaw...)
+		if dropCount := len(queue) - t.options.MaxQueueSize; dropCount > 0 {
+			t.log.Warn("databeat: re-queue overflow, dropping oldest raw events", slog.Int("dropped", dropCount))
+			t.alerts.drop(ReasonRequeueOverflow, dropCount, rawEventNames(queue[:dropCount]))
+			queue = queue[dropCount:]
+		}
+		t.queueRaw = append(t.queueRaw[:0], queue...)
+		return
+	}
+
 	if len(events) >= t.options.MaxQueueSize {
 		dropCount := len(t.queueRaw) + len(events) - t.options.MaxQueueSize
 		t.log.Warn("databeat: re-queue overflow, dropping raw events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonRequeueOverflow, dropCount, rawEventNames(events))
 		t.queueRaw = t.queueRaw[:0]
 		events = events[len(events)-t.options.MaxQueueSize:]
 	} else if totalSize := len(t.queueRaw) + len(events); totalSize > t.options.MaxQueueSize {
@@ -573,6 +715,7 @@ func (t *Databeat) requeueRaw(events []*proto.RawEvent) {
 			dropCount = len(t.queueRaw)
 		}
 		t.log.Warn("databeat: re-queue overflow, dropping oldest raw events", slog.Int("dropped", dropCount))
+		t.alerts.drop(ReasonRequeueOverflow, dropCount, rawEventNames(t.queueRaw[:dropCount]))
 		t.queueRaw = t.queueRaw[dropCount:]
 	}
 	t.queueRaw = append(t.queueRaw, events...)
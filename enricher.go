@@ -0,0 +1,344 @@
+package databeat
+
+import (
+	"log/slog"
+)
+
+// Enricher decorates a batch of events tracked together from the same
+// origin, after they are validated and before they are queued. It returns
+// the events to track, which allows an enricher to drop events. Props set
+// by enrichers are not validated, see ValidationOptions.
+//
+// Enrichers are applied to both Event and RawEvent, raw events are passed
+// to the chain as an Event view and the changes are written back. Events
+// must be changed in place; as the raw event of a copy is unknown, copies
+// of raw events are dropped and reported with ReasonReplacedEvent.
+type Enricher interface {
+	Enrich(t *Databeat, from From, events []*Event) []*Event
+}
+
+// EnricherFunc is an adapter to allow the use of ordinary functions as
+// an Enricher.
+type EnricherFunc func(t *Databeat, from From, events []*Event) []*Event
+
+func (f EnricherFunc) Enrich(t *Databeat, from From, events []*Event) []*Event {
+	return f(t, from, events)
+}
+
+// DefaultEnrichers is the chain of built-in enrichers. To add your own
+// enrichers, or disable a built-in one, set Options.Enrichers to a new
+// chain, ie. append(databeat.DefaultEnrichers, myEnricher).
+var DefaultEnrichers = []Enricher{
+	UserEnricher,
+	SessionEnricher,
+	ProjectEnricher,
+	SourceEnricher,
+	DeviceEnricher,
+	CountryEnricher,
+	BotEnricher,
+	AttributionEnricher,
+	LocaleEnricher,
+	GlobalContextEnricher,
+	TrackerEnricher,
+}
+
+// UserEnricher sets the user id and ident of events from the user id and
+// http request, respecting Options.Privacy.
+var UserEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.UserHTTPRequest == nil && from.UserID == "" && from.ProjectID == 0 {
+		return events
+	}
+
+	var uid string
+	var ident Ident
+	if from.UserHTTPRequest != nil || from.UserID != "" {
+		uid, ident = GenUserIDFromRequest(from.UserHTTPRequest, from.UserID, t.options.Privacy)
+	}
+
+	// Derive a private anonymous id from the client address if enabled
+	if from.UserID == "" && from.UserHTTPRequest != nil && t.options.Privacy.AnonIPHash {
+		ip := t.options.ClientIP.ClientIP(from.UserHTTPRequest)
+		if ip.IsValid() {
+			uid, ident = GenAnonUserID(ip, from.UserHTTPRequest.Header.Get("User-Agent"), t.options.Privacy)
+		}
+	}
+
+	// Set ident to service if no user details are passed, and project id is passed
+	if uid == "" && from.ProjectID > 0 {
+		ident = IDENT_SERVICE
+	}
+
+	for _, ev := range events {
+		if ev.UserID == nil || *ev.UserID == "" {
+			uidCopy := uid
+			ev.UserID = &uidCopy
+			ev.Ident = uint8(ident)
+		}
+	}
+	return events
+})
+
+// SessionEnricher sets the session id of events to From.SessionID, unless set.
+var SessionEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.SessionID == "" {
+		return events
+	}
+	for _, ev := range events {
+		if ev.SessionID == nil || *ev.SessionID == "" {
+			sessionID := from.SessionID
+			ev.SessionID = &sessionID
+		}
+	}
+	return events
+})
+
+// ProjectEnricher sets the project id of events if passed.
+var ProjectEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.ProjectID > 0 {
+		for _, ev := range events {
+			ev.ProjectID = from.ProjectID
+		}
+	}
+	return events
+})
+
+// SourceEnricher sets the source of events to From.Source or the route of
+// the request, unless set. See Options.Source.
+var SourceEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	source := from.Source
+	if source == "" && from.UserHTTPRequest != nil {
+		source = SourceFromRequest(from.UserHTTPRequest, t.options.Source)
+	}
+	if source == "" {
+		return events
+	}
+	for _, ev := range events {
+		if ev.Source == "" {
+			ev.Source = source
+		}
+	}
+	return events
+})
+
+// DeviceEnricher sets the device of events from the User-Agent and Client
+// Hints of the request, and the `deviceModel` prop if known.
+var DeviceEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.UserHTTPRequest == nil {
+		return events
+	}
+	device, model := DeviceFromRequest(from.UserHTTPRequest)
+	for _, ev := range events {
+		if device != nil {
+			d := *device // copy
+			ev.Device = &d
+		}
+		if model != "" {
+			setDefaultProps(ev, map[string]string{"deviceModel": model})
+		}
+	}
+	return events
+})
+
+// CountryEnricher sets the country of events with Options.CountryResolver.
+var CountryEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.UserHTTPRequest == nil {
+		return events
+	}
+	countryCode := t.options.CountryResolver.CountryCode(from.UserHTTPRequest)
+	if countryCode == "" {
+		return events
+	}
+	for _, ev := range events {
+		cc := countryCode
+		ev.CountryCode = &cc
+	}
+	return events
+})
+
+// BotEnricher applies Options.Bots to events of user requests made by bots.
+var BotEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.UserHTTPRequest == nil {
+		return events
+	}
+	ok, reason := t.options.Bots.check(from.UserHTTPRequest)
+	if !ok {
+		t.alerts.drop(ReasonBot, len(events), eventNames(events))
+		return nil
+	}
+	if reason != "" {
+		for _, ev := range events {
+			tagBotEvent(ev, reason)
+		}
+	}
+	return events
+})
+
+// AttributionEnricher adds the marketing attribution of the request to
+// event props, if Options.Attribution is enabled.
+var AttributionEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.UserHTTPRequest == nil || !t.options.Attribution.Enabled {
+		return events
+	}
+	var attribution map[string]string
+	if a, ok := AttributionFromRequest(from.UserHTTPRequest); ok {
+		attribution = a.Props("")
+	}
+	for _, ev := range events {
+		t.setAttributionProps(ev, attribution)
+	}
+	return events
+})
+
+// LocaleEnricher adds the locale and timezone of the request to event
+// props, if Options.Locale is enabled.
+var LocaleEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if from.UserHTTPRequest == nil || !t.options.Locale.Enabled {
+		return events
+	}
+	locale := LocaleFromRequest(from.UserHTTPRequest, t.options.Locale).Props()
+	if len(locale) == 0 {
+		return events
+	}
+	for _, ev := range events {
+		setDefaultProps(ev, locale)
+	}
+	return events
+})
+
+// TrackerEnricher sets the `_tracker` prop of events.
+var TrackerEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	for _, ev := range events {
+		if ev.Props == nil {
+			ev.Props = map[string]string{}
+		}
+		ev.Props["_tracker"] = "go-databeat"
+	}
+	return events
+})
+
+func (t *Databeat) enrich(from From, events []*Event) []*Event {
+	for _, e := range t.options.Enrichers {
+		if len(events) == 0 {
+			break
+		}
+		events = e.Enrich(t, from, events)
+	}
+	return events
+}
+
+func (t *Databeat) enrichRaw(from From, rawEvents []*RawEvent) []*RawEvent {
+	return t.applyToRaw(rawEvents, func(events []*Event) []*Event {
+		return t.enrich(from, events)
+	})
+}
+
+// applyToRaw applies fn to the Event views of raw events, and writes the
+// changes back to the raw events returned by fn. Views replaced by fn are
+// dropped, as their raw event is unknown.
+func (t *Databeat) applyToRaw(rawEvents []*RawEvent, fn func(events []*Event) []*Event) []*RawEvent {
+	views := make([]*Event, len(rawEvents))
+	index := make(map[*Event]int, len(rawEvents))
+	for i, raw := range rawEvents {
+		views[i] = eventFromRaw(raw)
+		index[views[i]] = i
+	}
+
+	views = fn(views)
+
+	events := make([]*RawEvent, 0, len(views))
+	var replaced []*Event
+	for _, ev := range views {
+		if i, ok := index[ev]; ok {
+			copyEventToRaw(ev, rawEvents[i])
+			events = append(events, rawEvents[i])
+			continue
+		}
+		// The view was replaced, ie. by an enricher copying events
+		replaced = append(replaced, ev)
+	}
+	if len(replaced) > 0 {
+		if t.warnedRawView.CompareAndSwap(false, true) {
+			t.log.Warn("databeat: dropped raw events replaced by an enricher, events must be changed in place", slog.String("event", replaced[0].Event))
+		}
+		t.alerts.drop(ReasonReplacedEvent, len(replaced), eventNames(replaced))
+	}
+	return events
+}
+
+// eventFromRaw returns an Event view of a raw event, sharing its maps.
+func eventFromRaw(raw *RawEvent) *Event {
+	ev := &Event{
+		Event:       raw.Event,
+		ProjectID:   raw.ProjectID,
+		Source:      raw.Source,
+		Ident:       raw.Ident,
+		UserID:      raw.UserID,
+		SessionID:   raw.SessionID,
+		CountryCode: raw.CountryCode,
+		Props:       raw.Props,
+		Nums:        raw.Nums,
+		Etc:         raw.Etc,
+	}
+	if raw.DeviceType != nil || raw.DeviceOS != nil || raw.DeviceOSVersion != nil || raw.DeviceBrowser != nil || raw.DeviceBrowserVersion != nil {
+		ev.Device = &Device{
+			Type:           derefString(raw.DeviceType),
+			OS:             derefString(raw.DeviceOS),
+			OSVersion:      derefString(raw.DeviceOSVersion),
+			Browser:        derefString(raw.DeviceBrowser),
+			BrowserVersion: derefString(raw.DeviceBrowserVersion),
+		}
+	}
+	return ev
+}
+
+// copyEventToRaw writes the fields of an Event view back to the raw event.
+func copyEventToRaw(ev *Event, raw *RawEvent) {
+	raw.Event = ev.Event
+	raw.ProjectID = ev.ProjectID
+	raw.Source = ev.Source
+	raw.Ident = ev.Ident
+	raw.UserID = ev.UserID
+	raw.SessionID = ev.SessionID
+	raw.CountryCode = ev.CountryCode
+	raw.Props = ev.Props
+	raw.Nums = ev.Nums
+	raw.Etc = ev.Etc
+	if ev.Device != nil {
+		raw.DeviceType = stringPtr(ev.Device.Type, raw.DeviceType)
+		raw.DeviceOS = stringPtr(ev.Device.OS, raw.DeviceOS)
+		raw.DeviceOSVersion = stringPtr(ev.Device.OSVersion, raw.DeviceOSVersion)
+		raw.DeviceBrowser = stringPtr(ev.Device.Browser, raw.DeviceBrowser)
+		raw.DeviceBrowserVersion = stringPtr(ev.Device.BrowserVersion, raw.DeviceBrowserVersion)
+	}
+}
+
+// setDefaultProps adds props to the event without overwriting explicit values.
+func setDefaultProps(ev *Event, props map[string]string) {
+	if len(props) == 0 {
+		return
+	}
+	if ev.Props == nil {
+		ev.Props = make(map[string]string, len(props))
+	}
+	for k, v := range props {
+		if _, ok := ev.Props[k]; !ok {
+			ev.Props[k] = v
+		}
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// stringPtr returns a pointer to s, keeping the previous pointer if s is
+// empty and unchanged.
+func stringPtr(s string, prev *string) *string {
+	if s == "" && (prev == nil || *prev == "") {
+		return prev
+	}
+	return &s
+}
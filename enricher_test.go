@@ -0,0 +1,61 @@
+package databeat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnrichRawReplacedEvents(t *testing.T) {
+	// Drops the first event, and copies the second one
+	cloner := EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+		clone := *events[1]
+		clone.Props = map[string]string{"cloned": "true"}
+		return []*Event{&clone}
+	})
+	alerts := make(chan Alert, 1)
+	opts := DefaultOptions
+	opts.Enrichers = []Enricher{cloner}
+	opts.Alerts.OnDrop = func(alert Alert) { alerts <- alert }
+	dbeat := newTestClient(t, opts)
+
+	ts := time.Unix(100, 0).UTC()
+	raws := dbeat.enrichRaw(From{}, []*RawEvent{
+		{App: String("a"), TS: &ts, Event: "A"},
+		{App: String("b"), Event: "B"},
+	})
+	if len(raws) != 0 {
+		t.Errorf("replaced raw events should be dropped, got %+v", raws[0])
+	}
+
+	select {
+	case alert := <-alerts:
+		if alert.Reason != ReasonReplacedEvent || alert.Count != 1 || alert.Events[0] != "B" {
+			t.Errorf("unexpected alert %+v", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alert")
+	}
+}
+
+func TestEnrichRawInPlace(t *testing.T) {
+	// Drops the first event, and changes the second one in place
+	dropper := EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+		events[1].Props = map[string]string{"changed": "true"}
+		return events[1:]
+	})
+	opts := DefaultOptions
+	opts.Enrichers = []Enricher{dropper}
+	dbeat := newTestClient(t, opts)
+
+	ts := time.Unix(100, 0).UTC()
+	raws := dbeat.enrichRaw(From{}, []*RawEvent{
+		{App: String("a"), TS: &ts, Event: "A"},
+		{App: String("b"), Event: "B", DeviceType: String("desktop")},
+	})
+	if len(raws) != 1 {
+		t.Fatalf("got %d raw events, want 1", len(raws))
+	}
+	if raws[0].Event != "B" || *raws[0].App != "b" || raws[0].TS != nil || *raws[0].DeviceType != "desktop" || raws[0].Props["changed"] != "true" {
+		t.Errorf("unexpected raw event %+v", raws[0])
+	}
+}
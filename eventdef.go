@@ -0,0 +1,84 @@
+package databeat
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// EventTracker is implemented by Databeat and Scope.
+type EventTracker interface {
+	TrackEvent(from From, events ...Event)
+}
+
+var (
+	_ EventTracker = &Databeat{}
+	_ EventTracker = &Scope{}
+)
+
+// EventDef is a typed event definition, where the fields of the struct
+// type T define the schema of the event props. Fields are mapped with
+// `databeat:"name,num|str,omitempty"` tags, numeric fields are set on
+// Event.Nums and the others on Event.Props by default.
+//
+//	type Login struct {
+//		Method  string `databeat:"method"`
+//		Retries int    `databeat:"retries,omitempty"`
+//	}
+//
+//	var LoginEvent = databeat.NewEventDef[Login]("LOGIN")
+//
+//	LoginEvent.Track(dbeat, databeat.From{UserID: uid}, Login{Method: "email"})
+type EventDef[T any] struct {
+	name   string
+	fields []structField
+}
+
+// NewEventDef defines the event `name` with the props schema T, and
+// registers it, see RegisteredEventTypes. It panics if T is not a struct
+// or has invalid tags, so definitions are best declared as package vars.
+func NewEventDef[T any](name string) *EventDef[T] {
+	fields, err := structFields(reflect.TypeFor[T]())
+	if err != nil {
+		panic(err)
+	}
+	registerEventType(name)
+	return &EventDef[T]{name: name, fields: fields}
+}
+
+func (d *EventDef[T]) Name() string {
+	return d.name
+}
+
+// Event returns the event with the props of `v`, see StructToEventProps.
+// Nested values are set on Event.Etc, and flattened when the event is
+// tracked if Options.Flatten is enabled.
+func (d *EventDef[T]) Event(v T) Event {
+	ev := Event{Event: d.name, Props: map[string]string{}, Nums: map[string]float64{}}
+	structToEvent(&ev, reflect.ValueOf(&v).Elem(), d.fields)
+	return ev
+}
+
+// Track tracks the event with the props of `v`.
+func (d *EventDef[T]) Track(tracker EventTracker, from From, v T) {
+	tracker.TrackEvent(from, d.Event(v))
+}
+
+var registeredEventTypes sync.Map // map[string]struct{}
+
+func registerEventType(name string) {
+	registeredEventTypes.Store(name, struct{}{})
+}
+
+// RegisteredEventTypes returns the names of the events defined with
+// NewEventDef. They are added to a Registry when event types are first
+// added to it, ie. from Options.AssertEventTypes.
+func RegisteredEventTypes() []string {
+	var names []string
+	registeredEventTypes.Range(func(k, _ any) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,205 @@
+package databeat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+type FlattenOptions struct {
+	// Enabled flattens the Event.Etc values of tracked events into props,
+	// before they are validated. Otherwise Event.Etc is not sent.
+	Enabled bool
+
+	// MaxDepth is the maximum nesting depth of flattened keys, ie.
+	// "cart.items.0.sku" has a depth of 4. Deeper values are dropped.
+	MaxDepth int
+
+	// MaxKeys is the maximum number of flattened keys. Further values are
+	// dropped.
+	MaxKeys int
+
+	// JSONLists encodes slices and arrays as a JSON string prop, instead
+	// of flattening them with index keys.
+	JSONLists bool
+}
+
+var DefaultFlattenOptions = FlattenOptions{
+	Enabled: false, MaxDepth: 5, MaxKeys: 100, JSONLists: false,
+}
+
+type flattener struct {
+	opts     FlattenOptions
+	strProps map[string]string
+	numProps map[string]float64
+	dropped  []string
+}
+
+func newFlattener(opts FlattenOptions) *flattener {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultFlattenOptions.MaxDepth
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = DefaultFlattenOptions.MaxKeys
+	}
+	return &flattener{opts: opts, strProps: map[string]string{}, numProps: map[string]float64{}}
+}
+
+func flattenProps(values map[string]interface{}, opts FlattenOptions) (map[string]string, map[string]float64, []string) {
+	f := newFlattener(opts)
+
+	// Sort keys so key limits are applied deterministically
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.flatten(k, reflect.ValueOf(values[k]), propAuto, 1)
+	}
+
+	return f.strProps, f.numProps, f.dropped
+}
+
+func (f *flattener) numKeys() int {
+	return len(f.strProps) + len(f.numProps)
+}
+
+// flatten adds the value `v` as the prop `key`, or the props of its nested
+// values with dotted keys. The `kind` of a struct field tag applies to
+// scalar values, see convertValue.
+func (f *flattener) flatten(key string, v reflect.Value, kind propKind, depth int) {
+	v, ok := indirect(v)
+	if !ok {
+		return
+	}
+	if str, num, k, ok := convertValue(v, kind); ok {
+		if f.numKeys() >= f.opts.MaxKeys {
+			f.dropped = append(f.dropped, key)
+		} else if k == propStr {
+			f.strProps[key] = str
+		} else {
+			f.numProps[key] = num
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if f.opts.JSONLists {
+			data, err := json.Marshal(v.Interface())
+			if err != nil {
+				f.dropped = append(f.dropped, key)
+				return
+			}
+			f.flatten(key, reflect.ValueOf(string(data)), propStr, depth)
+			return
+		}
+		if depth >= f.opts.MaxDepth && v.Len() > 0 {
+			f.dropped = append(f.dropped, key)
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			f.flatten(key+"."+strconv.Itoa(i), v.Index(i), propAuto, depth+1)
+		}
+
+	case reflect.Map:
+		if depth >= f.opts.MaxDepth && v.Len() > 0 {
+			f.dropped = append(f.dropped, key)
+			return
+		}
+		f.flattenMap(key+".", v, depth+1)
+
+	case reflect.Struct:
+		fields, err := structFields(v.Type())
+		if err != nil {
+			f.dropped = append(f.dropped, key)
+			return
+		}
+		if depth >= f.opts.MaxDepth && len(fields) > 0 {
+			f.dropped = append(f.dropped, key)
+			return
+		}
+		f.flattenStruct(key+".", v, fields, depth+1)
+
+	default:
+		// funcs, chans, etc.
+		f.dropped = append(f.dropped, key)
+	}
+}
+
+// flattenMap flattens the values of a map with sorted keys.
+func (f *flattener) flattenMap(prefix string, v reflect.Value, depth int) {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k := fmt.Sprint(iter.Key().Interface())
+		keys = append(keys, k)
+		values[k] = iter.Value()
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.flatten(prefix+k, values[k], propAuto, depth)
+	}
+}
+
+// flattenStruct flattens the fields of a struct per their tags.
+func (f *flattener) flattenStruct(prefix string, v reflect.Value, fields []structField, depth int) {
+	for _, field := range fields {
+		fv, err := v.FieldByIndexErr(field.index)
+		if err != nil {
+			// nil embedded pointer
+			continue
+		}
+		if field.omitempty && fv.IsZero() {
+			continue
+		}
+		f.flatten(prefix+field.name, fv, field.kind, depth)
+	}
+}
+
+// EtcEnricher flattens the Event.Etc values, which are not sent, into
+// Props and Nums per Options.Flatten. Explicit props are not overwritten.
+//
+// With FlattenOptions.Enabled, Event.Etc of tracked events is flattened
+// before validation, so this enricher is only needed by chains with
+// enrichers which set Event.Etc.
+var EtcEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	t.flattenEtc(events)
+	return events
+})
+
+// flattenEtc flattens the Event.Etc values into Props and Nums, and reports
+// the dropped keys.
+func (t *Databeat) flattenEtc(events []*Event) {
+	for _, ev := range events {
+		if len(ev.Etc) == 0 {
+			continue
+		}
+		strProps, numProps, dropped := flattenProps(ev.Etc, t.options.Flatten)
+		setDefaultProps(ev, strProps)
+		if len(numProps) > 0 {
+			if ev.Nums == nil {
+				ev.Nums = make(map[string]float64, len(numProps))
+			}
+			for k, v := range numProps {
+				if _, ok := ev.Nums[k]; !ok {
+					ev.Nums[k] = v
+				}
+			}
+		}
+		if len(dropped) > 0 {
+			t.log.Warn(fmt.Sprintf("databeat: dropped %d nested props of event %s", len(dropped), ev.Event), slog.Any("dropped", dropped))
+			errs := make([]ValidationError, len(dropped))
+			for i, key := range dropped {
+				errs[i] = ValidationError{Event: ev.Event, Prop: key, Reason: ReasonFlatten}
+			}
+			t.alerts.drop(ReasonFlatten, len(dropped), []string{ev.Event}, errs...)
+		}
+		ev.Etc = nil
+	}
+}
@@ -0,0 +1,65 @@
+package databeat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlattenEtcValidated(t *testing.T) {
+	var captured []Event
+	opts := DefaultOptions
+	opts.Flatten.Enabled = true
+	opts.Validation = ValidationOptions{
+		Schemas: map[string]EventSchema{
+			"LOGIN": {Props: map[string]PropRule{"method": {}, "cart.sku": {Type: PropTypeString}}},
+		},
+		Mode: ValidationDrop,
+	}
+	dbeat := newCaptureClient(t, opts, &captured)
+
+	dbeat.TrackEvent(From{}, Event{Event: "LOGIN", Etc: map[string]interface{}{"cart": map[string]any{"sku": "a"}}})
+	dbeat.TrackEvent(From{}, Event{Event: "LOGIN", Etc: map[string]interface{}{"cart": map[string]any{"qty": 2}}})
+
+	if len(captured) != 1 || captured[0].Props["cart.sku"] != "a" || captured[0].Etc != nil {
+		t.Errorf("unexpected events %+v", captured)
+	}
+}
+
+func TestFlattenDisabled(t *testing.T) {
+	var captured []Event
+	dbeat := newCaptureClient(t, DefaultOptions, &captured)
+
+	dbeat.TrackEvent(From{}, Event{Event: "A", Etc: map[string]interface{}{"local": "x"}})
+
+	if len(captured) != 1 || captured[0].Props["local"] != "" || captured[0].Etc["local"] != "x" {
+		t.Errorf("Etc should be left untouched, got %+v", captured)
+	}
+}
+
+func TestFlattenDropAlert(t *testing.T) {
+	alerts := make(chan Alert, 2)
+	opts := DefaultOptions
+	opts.Flatten.Enabled = true
+	opts.Flatten.MaxKeys = 1
+	opts.Alerts.OnDrop = func(alert Alert) { alerts <- alert }
+	dbeat := newTestClient(t, opts)
+
+	dbeat.TrackEvent(From{}, Event{Event: "A", Etc: map[string]interface{}{"a": 1, "b": 2}})
+
+	for {
+		select {
+		case alert := <-alerts:
+			if alert.Reason != ReasonFlatten {
+				// ie. ReasonNotRunning, as the client is not running
+				continue
+			}
+			want := ValidationError{Event: "A", Prop: "b", Reason: ReasonFlatten}
+			if alert.Count != 1 || len(alert.Errors) != 1 || alert.Errors[0] != want {
+				t.Errorf("unexpected alert %+v", alert)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatal("no alert")
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package databeat
+
+import (
+	"os"
+	"path"
+	"runtime/debug"
+)
+
+// GlobalContextOptions configures the static props attached to every event
+// tracked by the client. Props set explicitly on an event are never
+// overwritten.
+type GlobalContextOptions struct {
+	// BuildInfo adds the `service`, `version` and `commit` props from the
+	// build info of the main module.
+	BuildInfo bool
+
+	// Hostname adds the `host` prop.
+	Hostname bool
+
+	// Kubernetes adds the `pod`, `namespace` and `node` props from the
+	// POD_NAME, POD_NAMESPACE and NODE_NAME environment variables, as
+	// commonly exposed with the downward API.
+	Kubernetes bool
+
+	// Props are static props such as `region`, which take precedence over
+	// the detected values above.
+	Props map[string]string
+}
+
+var DefaultGlobalContextOptions = GlobalContextOptions{
+	BuildInfo: false, Hostname: false, Kubernetes: false, Props: nil,
+}
+
+// GlobalContextProps returns the static props described by `opts`.
+func GlobalContextProps(opts GlobalContextOptions) map[string]string {
+	props := map[string]string{}
+
+	if opts.BuildInfo {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			if info.Main.Path != "" {
+				props["service"] = path.Base(info.Main.Path)
+			}
+			if v := info.Main.Version; v != "" && v != "(devel)" {
+				props["version"] = v
+			}
+			for _, s := range info.Settings {
+				if s.Key == "vcs.revision" && s.Value != "" {
+					props["commit"] = s.Value[:min(12, len(s.Value))]
+				}
+			}
+		}
+	}
+
+	if opts.Hostname {
+		if host, err := os.Hostname(); err == nil && host != "" {
+			props["host"] = host
+		}
+	}
+
+	if opts.Kubernetes {
+		for key, env := range map[string]string{"pod": "POD_NAME", "namespace": "POD_NAMESPACE", "node": "NODE_NAME"} {
+			if v := os.Getenv(env); v != "" {
+				props[key] = v
+			}
+		}
+	}
+
+	for k, v := range opts.Props {
+		props[k] = v
+	}
+
+	return props
+}
+
+// GlobalContextEnricher adds the props of Options.GlobalContext to events.
+var GlobalContextEnricher = EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+	if len(t.globalProps) == 0 {
+		return events
+	}
+	for _, ev := range events {
+		setDefaultProps(ev, t.globalProps)
+	}
+	return events
+})
@@ -0,0 +1,280 @@
+// Package grpcbeat provides gRPC server and client interceptors which track
+// a databeat event for every RPC. Events carry a `rpcKind` prop of "server"
+// or "client", so RPCs tracked on both sides can be told apart.
+package grpcbeat
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	databeat "github.com/horizon-games/go-databeat"
+	"github.com/horizon-games/go-databeat/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys used to propagate the tracking identity between internal
+// services, see Options.Propagate and FromMetadata.
+const (
+	MetadataUserID    = "databeat-user-id"
+	MetadataProjectID = "databeat-project-id"
+	MetadataSessionID = "databeat-session-id"
+)
+
+type Options struct {
+	// Event is the name of the tracked events.
+	Event string
+
+	// From extracts the user identity of an incoming RPC, ie. from an auth
+	// token. The metadata of the RPC is always used as UserHTTPRequest.
+	//
+	// Set it to FromMetadata to use the identity propagated by the client
+	// interceptors. As the metadata is set by the caller, only do so for
+	// servers which are not reachable by untrusted clients.
+	From func(ctx context.Context, md metadata.MD) databeat.From
+
+	// Exclude is an optional func to skip tracking of a method.
+	Exclude func(fullMethod string) bool
+
+	// Propagate adds the identity of the context to the outgoing metadata
+	// in the client interceptors. Only enable it for calls to internal
+	// services, as it discloses user ids to the server.
+	Propagate bool
+}
+
+var DefaultOptions = Options{
+	Event:     proto.EventType_REQUEST.String(),
+	From:      nil,
+	Exclude:   IsHealthCheck,
+	Propagate: false,
+}
+
+// Values of the `rpcKind` prop, telling apart the events tracked by the
+// server and client interceptors for the same RPC.
+const (
+	KindServer = "server"
+	KindClient = "client"
+)
+
+// FromMetadata returns the identity propagated by the client interceptors.
+// It trusts the metadata set by the caller, so it is meant for internal
+// services only.
+func FromMetadata(ctx context.Context, md metadata.MD) databeat.From {
+	var from databeat.From
+	if v := md.Get(MetadataUserID); len(v) > 0 {
+		from.UserID = v[0]
+	}
+	if v := md.Get(MetadataProjectID); len(v) > 0 {
+		from.ProjectID, _ = strconv.ParseUint(v[0], 10, 64)
+	}
+	if v := md.Get(MetadataSessionID); len(v) > 0 {
+		from.SessionID = v[0]
+	}
+	return from
+}
+
+// IsHealthCheck reports whether the method belongs to the health or
+// reflection services.
+func IsHealthCheck(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.") || strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+// RequestFromContext returns a http request equivalent of an incoming RPC,
+// carrying its metadata as headers and the peer address as RemoteAddr, so
+// it can be used as From.UserHTTPRequest for device, country and client IP
+// detection.
+func RequestFromContext(ctx context.Context, fullMethod string) *http.Request {
+	r := &http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: fullMethod},
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     http.Header{},
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, values := range md {
+			if strings.HasSuffix(k, "-bin") || strings.HasPrefix(k, ":") {
+				continue
+			}
+			for _, v := range values {
+				r.Header.Add(k, v)
+			}
+		}
+		if v := md.Get(":authority"); len(v) > 0 {
+			r.Host = v[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r.WithContext(ctx)
+}
+
+// UnaryServerInterceptor tracks an event for every unary RPC, and attaches
+// the tracking identity to the handler context, see databeat.NewContext.
+func UnaryServerInterceptor(dbeat *databeat.Databeat, opts ...Options) grpc.UnaryServerInterceptor {
+	options := options(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if options.skip(dbeat, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, from := options.serverContext(ctx, info.FullMethod)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		options.track(dbeat, KindServer, from, info.FullMethod, err, time.Since(start))
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor tracks an event for every streaming RPC when the
+// stream ends, and attaches the tracking identity to the stream context.
+func StreamServerInterceptor(dbeat *databeat.Databeat, opts ...Options) grpc.StreamServerInterceptor {
+	options := options(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if options.skip(dbeat, info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, from := options.serverContext(ss.Context(), info.FullMethod)
+
+		start := time.Now()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		options.track(dbeat, KindServer, from, info.FullMethod, err, time.Since(start))
+
+		return err
+	}
+}
+
+// UnaryClientInterceptor tracks an event for every outgoing unary RPC with
+// the identity of the context, and propagates it to the server if
+// Options.Propagate is set.
+func UnaryClientInterceptor(dbeat *databeat.Databeat, opts ...Options) grpc.UnaryClientInterceptor {
+	options := options(opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		ctx, from := options.clientContext(ctx)
+		if options.skip(dbeat, method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		options.track(dbeat, KindClient, from, method, err, time.Since(start))
+
+		return err
+	}
+}
+
+// StreamClientInterceptor tracks an event for every outgoing streaming RPC
+// once the stream is established, and propagates the identity of the
+// context to the server if Options.Propagate is set.
+func StreamClientInterceptor(dbeat *databeat.Databeat, opts ...Options) grpc.StreamClientInterceptor {
+	options := options(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, from := options.clientContext(ctx)
+		if options.skip(dbeat, method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		options.track(dbeat, KindClient, from, method, err, time.Since(start))
+
+		return cs, err
+	}
+}
+
+func options(opts []Options) Options {
+	options := DefaultOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.Event == "" {
+		options.Event = DefaultOptions.Event
+	}
+	return options
+}
+
+func (o Options) skip(dbeat *databeat.Databeat, fullMethod string) bool {
+	return !dbeat.Enabled || (o.Exclude != nil && o.Exclude(fullMethod))
+}
+
+func (o Options) serverContext(ctx context.Context, fullMethod string) (context.Context, databeat.From) {
+	var from databeat.From
+	if o.From != nil {
+		md, _ := metadata.FromIncomingContext(ctx)
+		from = o.From(ctx, md)
+	}
+	from.UserHTTPRequest = RequestFromContext(ctx, fullMethod)
+
+	ctx = databeat.NewContext(ctx, from)
+	from, _ = databeat.FromContext(ctx)
+	return ctx, from
+}
+
+func (o Options) clientContext(ctx context.Context) (context.Context, databeat.From) {
+	from, ok := databeat.FromContext(ctx)
+	if !ok || !o.Propagate {
+		return ctx, from
+	}
+
+	var kv []string
+	if from.UserID != "" {
+		kv = append(kv, MetadataUserID, from.UserID)
+	}
+	if from.ProjectID > 0 {
+		kv = append(kv, MetadataProjectID, strconv.FormatUint(from.ProjectID, 10))
+	}
+	if from.SessionID != "" {
+		kv = append(kv, MetadataSessionID, from.SessionID)
+	}
+	if len(kv) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, kv...)
+	}
+	return ctx, from
+}
+
+func (o Options) track(dbeat *databeat.Databeat, kind string, from databeat.From, fullMethod string, err error, latency time.Duration) {
+	service, method := splitMethod(fullMethod)
+	if from.Source == "" {
+		from.Source = fullMethod
+	}
+
+	dbeat.TrackEvent(from, databeat.Event{
+		Event: o.Event,
+		Props: map[string]string{
+			"rpcKind":    kind,
+			"rpcService": service,
+			"rpcMethod":  method,
+			"status":     status.Code(err).String(),
+		},
+		Nums: map[string]float64{
+			"latencyMs": float64(latency.Microseconds()) / 1000,
+		},
+	})
+}
+
+// splitMethod splits "/package.Service/Method" into its service and method.
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "", fullMethod
+	}
+	return service, method
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -13,6 +13,13 @@ type From struct {
 	UserID          string
 	UserHTTPRequest *http.Request
 	ProjectID       uint64
+
+	// Source is the default source of events, used instead of the path
+	// of UserHTTPRequest.
+	Source string
+
+	// SessionID is the default session id of events.
+	SessionID string
 }
 
 func TimeNow() *time.Time {
@@ -36,25 +43,23 @@ func DeviceFromUserAgent(userAgent string) *proto.Device {
 	device.Browser = strings.ToLower(ua.Name)
 	device.BrowserVersion = strings.ToLower(ua.Version)
 
-	if ua.Desktop {
+	if ua.Bot {
+		device.Type = "bot"
+	} else if ua.Desktop {
 		device.Type = "desktop"
 	} else if ua.Mobile {
 		device.Type = "mobile"
 	} else if ua.Tablet {
 		device.Type = "tablet"
-	} else if ua.Bot {
-		device.Type = "bot"
 	}
 
 	return device
 }
 
+// CountryCodeFromRequest returns the country code of the request using the
+// DefaultCountryResolver chain.
 func CountryCodeFromRequest(r *http.Request) string {
-	h := r.Header.Get("CF-IPCountry")
-	if h == "" {
-		return ""
-	}
-	return strings.ToUpper(h)
+	return DefaultCountryResolver.CountryCode(r)
 }
 
 func ServerDevice() *Device {
@@ -104,16 +109,14 @@ func EventTypes(eventTypesMap ...map[string]uint32) []string {
 	return keys
 }
 
-func validateEventTypes(eventTypes map[string]struct{}, events []*Event) (bool, []string, []*Event) {
-	if len(eventTypes) == 0 {
-		// skip if event types assert map is empty
-		return true, nil, events
-	}
+func validateEventTypes(registry *Registry, events []*Event) (bool, []string, []*Event) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
 
 	// quick validation check
 	valid := true
 	for _, ev := range events {
-		if _, ok := eventTypes[ev.Event]; !ok {
+		if !registry.allowed(ev.Event) {
 			valid = false
 			break
 		}
@@ -126,7 +129,7 @@ func validateEventTypes(eventTypes map[string]struct{}, events []*Event) (bool,
 	validEvents := []*Event{}
 	invalidNames := []string{}
 	for _, ev := range events {
-		if _, ok := eventTypes[ev.Event]; !ok {
+		if !registry.allowed(ev.Event) {
 			invalidNames = append(invalidNames, ev.Event)
 		} else {
 			validEvents = append(validEvents, ev)
@@ -0,0 +1,35 @@
+package databeat
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// newTestClient returns a client which is not running, and discards logs.
+func newTestClient(t *testing.T, opts Options) *Databeat {
+	t.Helper()
+	dbeat, err := NewDatabeatClient("http://localhost", "", slog.New(slog.NewTextHandler(io.Discard, nil)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dbeat
+}
+
+// newCaptureClient returns a test client with the enricher chain set to
+// `enrichers`, followed by an enricher recording the events in `captured`.
+func newCaptureClient(t *testing.T, opts Options, captured *[]Event, enrichers ...Enricher) *Databeat {
+	t.Helper()
+	opts.Enrichers = append(append([]Enricher{}, enrichers...), captureEnricher(captured))
+	return newTestClient(t, opts)
+}
+
+// captureEnricher records the events passing through the enricher chain.
+func captureEnricher(captured *[]Event) Enricher {
+	return EnricherFunc(func(t *Databeat, from From, events []*Event) []*Event {
+		for _, ev := range events {
+			*captured = append(*captured, *ev)
+		}
+		return events
+	})
+}
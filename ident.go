@@ -7,6 +7,7 @@ import (
 	"fmt"
 	mrand "math/rand"
 	"net/http"
+	"net/netip"
 )
 
 type Ident uint8
@@ -60,6 +61,20 @@ func GenUserIDFromRequest(r *http.Request, userID string, privacyOptions Privacy
 	return GenUserID(userID, privacyOptions)
 }
 
+// GenAnonUserID returns a private user id for an anonymous user derived from
+// their (truncated) client address and User-Agent. If the address is not
+// valid, a random anonymous id is returned instead.
+func GenAnonUserID(ip netip.Addr, userAgent string, privacyOptions PrivacyOptions) (string, Ident) {
+	if !ip.IsValid() {
+		return GenUserID("", privacyOptions)
+	}
+	anonID := fmt.Sprintf("%s:%s", TruncateIP(ip), userAgent)
+	if privacyOptions.ExtraSalt != "" {
+		anonID = fmt.Sprintf("%s:%s", anonID, privacyOptions.ExtraSalt)
+	}
+	return sha256Hex(anonID)[0:50], IDENT_PRIVATE
+}
+
 func GenSessionID() string {
 	bytes := make([]byte, 8)
 	if _, err := rand.Read(bytes); err != nil {
@@ -72,6 +87,10 @@ type PrivacyOptions struct {
 	UserIDHash    bool
 	UserAgentSalt bool
 	ExtraSalt     string
+
+	// AnonIPHash derives a private id for anonymous users from their
+	// truncated client address and User-Agent, instead of a random id.
+	AnonIPHash bool
 }
 
 var DefaultPrivacyOptions = PrivacyOptions{
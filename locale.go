@@ -0,0 +1,149 @@
+package databeat
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale is the locale context of a user request.
+type Locale struct {
+	Locale   string
+	Language string
+	Timezone string
+}
+
+type LocaleOptions struct {
+	// Enabled adds the locale, language and timezone of user requests to
+	// event props.
+	Enabled bool
+
+	// TimezoneHeader is the request header carrying an IANA timezone hint,
+	// ie. as set by the frontend from Intl.DateTimeFormat().resolvedOptions().
+	TimezoneHeader string
+
+	// TimezoneCookie is the cookie carrying an IANA timezone hint, used if
+	// the header is not present.
+	TimezoneCookie string
+}
+
+var DefaultLocaleOptions = LocaleOptions{
+	Enabled: false, TimezoneHeader: "X-Timezone", TimezoneCookie: "",
+}
+
+// LanguageRange is a language tag of the Accept-Language header with its
+// quality weight.
+type LanguageRange struct {
+	Tag     string
+	Quality float64
+}
+
+// ParseAcceptLanguage parses an Accept-Language header, ordered by quality.
+// Wildcards and ranges with a zero quality are skipped.
+func ParseAcceptLanguage(h string) []LanguageRange {
+	var ranges []LanguageRange
+	for _, part := range strings.Split(h, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" || len(tag) > 35 {
+			continue
+		}
+
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, LanguageRange{Tag: canonicalLanguageTag(tag), Quality: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Quality > ranges[j].Quality
+	})
+	return ranges
+}
+
+// LocaleFromRequest returns the primary locale of the request from its
+// Accept-Language header, and the timezone hint configured in `opts`.
+func LocaleFromRequest(r *http.Request, opts LocaleOptions) Locale {
+	var locale Locale
+	if r == nil {
+		return locale
+	}
+
+	if ranges := ParseAcceptLanguage(r.Header.Get("Accept-Language")); len(ranges) > 0 {
+		locale.Locale = ranges[0].Tag
+		locale.Language, _, _ = strings.Cut(locale.Locale, "-")
+	}
+
+	var tz string
+	if opts.TimezoneHeader != "" {
+		tz = r.Header.Get(opts.TimezoneHeader)
+	}
+	if tz == "" && opts.TimezoneCookie != "" {
+		if c, err := r.Cookie(opts.TimezoneCookie); err == nil {
+			tz = c.Value
+		}
+	}
+	if isTimezone(tz) {
+		locale.Timezone = tz
+	}
+
+	return locale
+}
+
+// Props returns the locale as event props.
+func (l Locale) Props() map[string]string {
+	props := map[string]string{}
+	if l.Locale != "" {
+		props["locale"] = l.Locale
+		props["language"] = l.Language
+	}
+	if l.Timezone != "" {
+		props["timezone"] = l.Timezone
+	}
+	return props
+}
+
+// canonicalLanguageTag formats a tag as language-Script-REGION, ie. "zh-Hant-TW".
+func canonicalLanguageTag(tag string) string {
+	parts := strings.Split(strings.ReplaceAll(tag, "_", "-"), "-")
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 2:
+			parts[i] = strings.ToUpper(p)
+		case len(p) == 4:
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// isTimezone reports whether tz looks like an IANA timezone name, without
+// requiring the tz database to be available.
+func isTimezone(tz string) bool {
+	if tz == "" || len(tz) > 64 {
+		return false
+	}
+	for _, c := range tz {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '/' || c == '_' || c == '-' || c == '+':
+		default:
+			return false
+		}
+	}
+	return true
+}
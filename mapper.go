@@ -0,0 +1,329 @@
+package databeat
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type propKind uint8
+
+const (
+	propAuto propKind = iota
+	propStr
+	propNum
+)
+
+// structField is the mapping of a struct field to a prop, parsed from
+// its `databeat:"name,num|str,omitempty"` tag.
+type structField struct {
+	index     []int
+	name      string
+	kind      propKind
+	omitempty bool
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	durationType      = reflect.TypeFor[time.Duration]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	stringerType      = reflect.TypeFor[fmt.Stringer]()
+)
+
+var structFieldsCache sync.Map // map[reflect.Type][]structField
+
+// StructToEventProps maps the fields of a struct, or pointer to a struct,
+// to props and nums without a JSON round trip. Fields are mapped with
+// `databeat:"name,num|str,omitempty"` tags, falling back to the name of
+// the `json` tag and then the field name. The mapping is cached per type.
+//
+// By default, numeric fields are nums and the others are props. Values
+// are converted as follows, including nested values:
+//   - time.Time is a RFC 3339 prop, or unix seconds with `num`
+//   - time.Duration is a num in milliseconds, or a prop such as "1.5s" with `str`
+//   - encoding.TextMarshaler and fmt.Stringer values are props, unless numeric,
+//     including methods with pointer receivers on addressable values
+//   - integers tagged `str` are formatted without loss of precision
+//   - nil pointers are omitted, others are dereferenced
+//   - embedded structs are inlined, other nested values are flattened
+//     into dotted keys per DefaultFlattenOptions, see StructToFlatProps
+func StructToEventProps(v any) (map[string]string, map[string]float64, error) {
+	strProps, numProps, _, err := structToFlatProps(v, DefaultFlattenOptions)
+	return strProps, numProps, err
+}
+
+// structToFlatProps maps a struct or a map to props and nums, flattening
+// nested values per `opts`, and returns the dropped keys.
+func structToFlatProps(v any, opts FlattenOptions) (map[string]string, map[string]float64, []string, error) {
+	f := newFlattener(opts)
+	rv, ok := indirect(reflect.ValueOf(v))
+	if !ok {
+		return f.strProps, f.numProps, nil, nil
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		fields, err := structFields(rv.Type())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !rv.CanAddr() {
+			// Copy, so methods with pointer receivers can be called
+			addr := reflect.New(rv.Type()).Elem()
+			addr.Set(rv)
+			rv = addr
+		}
+		f.flattenStruct("", rv, fields, 1)
+	case reflect.Map:
+		f.flattenMap("", rv, 1)
+	default:
+		return nil, nil, nil, fmt.Errorf("databeat: %s is not a struct or map", rv.Type())
+	}
+	return f.strProps, f.numProps, f.dropped, nil
+}
+
+// structFields returns the prop mapping of a struct type, cached per type.
+func structFields(t reflect.Type) ([]structField, error) {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("databeat: %s is not a struct", t)
+	}
+
+	fields, err := parseStructFields(t, nil, map[reflect.Type]bool{t: true})
+	if err != nil {
+		return nil, err
+	}
+
+	structFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+// parseStructFields parses the fields of a struct type, where `embedding`
+// are the types on the path of embedded structs, to stop at cycles.
+func parseStructFields(t reflect.Type, index []int, embedding map[reflect.Type]bool) ([]structField, error) {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("databeat")
+		if !hasTag {
+			tag = f.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int{}, index...), i)
+
+		// Inline embedded structs, like encoding/json
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				if embedding[ft] {
+					// Recursive embedding, like encoding/json
+					continue
+				}
+				embedding[ft] = true
+				embedded, err := parseStructFields(ft, fieldIndex, embedding)
+				delete(embedding, ft)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, embedded...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		field := structField{index: fieldIndex, name: f.Name}
+		if name != "" {
+			field.name = name
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "":
+			case "str", "string":
+				field.kind = propStr
+			case "num":
+				field.kind = propNum
+			case "omitempty":
+				field.omitempty = true
+			default:
+				if hasTag {
+					return nil, fmt.Errorf("databeat: invalid tag option %q on %s.%s", opt, t, f.Name)
+				}
+			}
+		}
+		if field.kind == propNum && !canBeNum(f.Type) {
+			return nil, fmt.Errorf("databeat: field %s.%s of type %s can not be a num", t, f.Name, f.Type)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func canBeNum(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t == timeType || isNumKind(t.Kind())
+}
+
+// structToEvent sets the fields of a struct value on the props and nums of
+// the event. Nested values are set on Event.Etc, so they are flattened per
+// Options.Flatten of the client tracking the event, if enabled.
+func structToEvent(ev *Event, v reflect.Value, fields []structField) {
+	for _, field := range fields {
+		fv, err := v.FieldByIndexErr(field.index)
+		if err != nil {
+			// nil embedded pointer
+			continue
+		}
+		if field.omitempty && fv.IsZero() {
+			continue
+		}
+		fv, ok := indirect(fv)
+		if !ok {
+			continue
+		}
+
+		str, num, kind, ok := convertValue(fv, field.kind)
+		switch {
+		case !ok:
+			if ev.Etc == nil {
+				ev.Etc = map[string]interface{}{}
+			}
+			if fv.CanAddr() {
+				// Keep the pointer for methods with pointer receivers
+				ev.Etc[field.name] = fv.Addr().Interface()
+			} else {
+				ev.Etc[field.name] = fv.Interface()
+			}
+		case kind == propStr:
+			if ev.Props == nil {
+				ev.Props = map[string]string{}
+			}
+			ev.Props[field.name] = str
+		default:
+			if ev.Nums == nil {
+				ev.Nums = map[string]float64{}
+			}
+			ev.Nums[field.name] = num
+		}
+	}
+}
+
+// indirect dereferences pointers and interfaces, and returns false for nil.
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+// convertValue converts a scalar value to a prop or a num, per the `kind`
+// of its struct tag. It returns false for nested values.
+func convertValue(v reflect.Value, kind propKind) (string, float64, propKind, bool) {
+	t := v.Type()
+	switch {
+	case t == timeType:
+		tm := v.Interface().(time.Time)
+		if kind == propNum {
+			return "", float64(tm.UnixNano()) / 1e9, propNum, true
+		}
+		return tm.Format(time.RFC3339Nano), 0, propStr, true
+
+	case t == durationType:
+		d := time.Duration(v.Int())
+		if kind == propStr {
+			return d.String(), 0, propStr, true
+		}
+		return "", float64(d) / float64(time.Millisecond), propNum, true
+
+	case kind != propNum && implements(v, textMarshalerType):
+		m := methodValue(v, textMarshalerType).(encoding.TextMarshaler)
+		if text, err := m.MarshalText(); err == nil {
+			return string(text), 0, propStr, true
+		}
+		return fmt.Sprint(m), 0, propStr, true
+
+	case isNumKind(v.Kind()):
+		if kind == propStr {
+			return strValue(v), 0, propStr, true
+		}
+		return "", numValue(v), propNum, true
+
+	case v.Kind() == reflect.String:
+		return v.String(), 0, propStr, true
+
+	case v.Kind() == reflect.Bool:
+		return strconv.FormatBool(v.Bool()), 0, propStr, true
+
+	case implements(v, stringerType):
+		return methodValue(v, stringerType).(fmt.Stringer).String(), 0, propStr, true
+
+	case v.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return string(v.Bytes()), 0, propStr, true
+	}
+	return "", 0, propAuto, false
+}
+
+// implements reports whether the value, or its address if addressable,
+// implements the interface type `it`, like encoding/json.
+func implements(v reflect.Value, it reflect.Type) bool {
+	return v.Type().Implements(it) || (v.CanAddr() && reflect.PointerTo(v.Type()).Implements(it))
+}
+
+// methodValue returns the value, or its address, implementing `it`.
+func methodValue(v reflect.Value, it reflect.Type) any {
+	if v.Type().Implements(it) {
+		return v.Interface()
+	}
+	return v.Addr().Interface()
+}
+
+func isNumKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func numValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return 0
+}
+
+func strValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
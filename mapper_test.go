@@ -0,0 +1,250 @@
+package databeat
+
+import (
+	"math/big"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mapperTestBase struct {
+	SessionID string `databeat:"sessionId"`
+}
+
+type mapperTestItem struct {
+	SKU     string    `json:"sku"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+type mapperTestProps struct {
+	mapperTestBase
+	*mapperTestItem
+
+	UserID  uint64        `databeat:"userId,str"`
+	Amount  float64       `databeat:"amount"`
+	Latency time.Duration `databeat:"latencyMs"`
+	Timeout time.Duration `databeat:"timeout,str"`
+	At      time.Time     `databeat:"at"`
+	AtUnix  time.Time     `databeat:"atUnix,num"`
+	IP      netip.Addr    `databeat:"ip"`
+	Plan    *string       `databeat:"plan"`
+	Coupon  *string       `databeat:"coupon"`
+	Note    string        `databeat:"note,omitempty"`
+	Cart    []mapperTestItem
+	Meta    map[string]any `json:"meta"`
+	Ignored string         `json:"-"`
+}
+
+var mapperTestTime = time.Date(2024, 5, 1, 12, 30, 0, 500_000_000, time.UTC)
+
+func TestStructToEventProps(t *testing.T) {
+	plan := "pro"
+	props := &mapperTestProps{
+		mapperTestBase: mapperTestBase{SessionID: "s1"},
+		mapperTestItem: &mapperTestItem{SKU: "a", AddedAt: mapperTestTime},
+		UserID:         1<<53 + 1,
+		Amount:         9.99,
+		Latency:        1500 * time.Millisecond,
+		Timeout:        2 * time.Second,
+		At:             mapperTestTime,
+		AtUnix:         mapperTestTime,
+		IP:             netip.MustParseAddr("10.0.0.1"),
+		Plan:           &plan,
+		Cart:           []mapperTestItem{{SKU: "b", AddedAt: mapperTestTime}},
+		Meta:           map[string]any{"ttl": time.Minute, "ip": netip.MustParseAddr("::1")},
+		Ignored:        "x",
+	}
+
+	strProps, numProps, err := StructToEventProps(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantStr := map[string]string{
+		"sessionId":      "s1",
+		"sku":            "a",
+		"addedAt":        "2024-05-01T12:30:00.5Z",
+		"userId":         "9007199254740993",
+		"timeout":        "2s",
+		"at":             "2024-05-01T12:30:00.5Z",
+		"ip":             "10.0.0.1",
+		"plan":           "pro",
+		"Cart.0.sku":     "b",
+		"Cart.0.addedAt": "2024-05-01T12:30:00.5Z",
+		"meta.ip":        "::1",
+	}
+	wantNum := map[string]float64{
+		"amount":    9.99,
+		"latencyMs": 1500,
+		"atUnix":    float64(mapperTestTime.UnixNano()) / 1e9,
+		"meta.ttl":  60000,
+	}
+	if !reflect.DeepEqual(strProps, wantStr) {
+		t.Errorf("props = %v, want %v", strProps, wantStr)
+	}
+	if !reflect.DeepEqual(numProps, wantNum) {
+		t.Errorf("nums = %v, want %v", numProps, wantNum)
+	}
+}
+
+type mapperTestBig struct {
+	Amount  *big.Int `databeat:"amount,str"`
+	Balance big.Int  `databeat:"balance"`
+	Nested  struct {
+		Total *big.Int `json:"total"`
+	} `json:"nested"`
+}
+
+func TestStructToEventPropsPointerMethods(t *testing.T) {
+	amount, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	v := mapperTestBig{Amount: amount}
+	v.Balance.SetInt64(1<<53 + 1)
+	v.Nested.Total = amount
+
+	want := map[string]string{
+		"amount":       "123456789012345678901234567890",
+		"balance":      "9007199254740993",
+		"nested.total": "123456789012345678901234567890",
+	}
+	for _, input := range []any{v, &v} {
+		strProps, _, err := StructToEventProps(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(strProps, want) {
+			t.Errorf("%T: props = %v, want %v", input, strProps, want)
+		}
+
+		strProps, _, dropped, err := StructToFlatProps(input, DefaultFlattenOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(strProps, want) || len(dropped) > 0 {
+			t.Errorf("%T: flat props = %v, dropped %v, want %v", input, strProps, dropped, want)
+		}
+	}
+}
+
+type mapperTestNode struct {
+	*mapperTestNode
+	V int `json:"v"`
+}
+
+func TestStructToEventPropsRecursiveEmbedding(t *testing.T) {
+	_, numProps, err := StructToEventProps(mapperTestNode{mapperTestNode: &mapperTestNode{V: 1}, V: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"v": 2}; !reflect.DeepEqual(numProps, want) {
+		t.Errorf("nums = %v, want %v", numProps, want)
+	}
+}
+
+func TestStructToEventPropsNilEmbedded(t *testing.T) {
+	strProps, _, err := StructToEventProps(mapperTestProps{Note: "n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := strProps["sku"]; ok {
+		t.Error("fields of a nil embedded pointer should be omitted")
+	}
+	if _, ok := strProps["plan"]; ok {
+		t.Error("nil pointers should be omitted")
+	}
+	if strProps["note"] != "n" {
+		t.Errorf("note = %q", strProps["note"])
+	}
+}
+
+func TestStructToEventPropsErrors(t *testing.T) {
+	type invalidNum struct {
+		Name string `databeat:"name,num"`
+	}
+	type invalidOption struct {
+		Name string `databeat:"name,bogus"`
+	}
+	for _, v := range []any{42, invalidNum{}, invalidOption{}} {
+		if _, _, err := StructToEventProps(v); err == nil {
+			t.Errorf("%T: expected an error", v)
+		}
+	}
+}
+
+func TestStructToFlatProps(t *testing.T) {
+	props := mapperTestProps{Cart: []mapperTestItem{{SKU: "a"}, {SKU: "b"}}}
+
+	strProps, _, dropped, err := StructToFlatProps(props, FlattenOptions{MaxDepth: 2, JSONLists: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"sku":"a","addedAt":"0001-01-01T00:00:00Z"},{"sku":"b","addedAt":"0001-01-01T00:00:00Z"}]`; strProps["Cart"] != want {
+		t.Errorf("Cart = %s, want %s", strProps["Cart"], want)
+	}
+	if len(dropped) != 0 {
+		t.Errorf("dropped = %v", dropped)
+	}
+
+	_, _, dropped, err = StructToFlatProps(props, FlattenOptions{MaxDepth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Cart.0", "Cart.1"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped = %v, want %v", dropped, want)
+	}
+}
+
+var mapperTestEvent = NewEventDef[mapperTestProps]("MAPPER_TEST")
+
+func TestEventDefFlatten(t *testing.T) {
+	var captured []Event
+	opts := DefaultOptions
+	opts.Flatten.Enabled = true
+	opts.Flatten.JSONLists = true
+	dbeat := newCaptureClient(t, opts, &captured)
+
+	ev := mapperTestEvent.Event(mapperTestProps{
+		Cart: []mapperTestItem{{SKU: "a", AddedAt: mapperTestTime}},
+		Meta: map[string]any{"at": mapperTestTime},
+	})
+	dbeat.TrackEvent(From{}, ev)
+
+	if len(captured) != 1 {
+		t.Fatalf("captured %d events", len(captured))
+	}
+	props := captured[0].Props
+	if want := `[{"sku":"a","addedAt":"2024-05-01T12:30:00.5Z"}]`; props["Cart"] != want {
+		t.Errorf("Cart = %s, want %s", props["Cart"], want)
+	}
+	if want := "2024-05-01T12:30:00.5Z"; props["meta.at"] != want {
+		t.Errorf("meta.at = %s, want %s", props["meta.at"], want)
+	}
+
+	amount := big.NewInt(1<<53 + 1)
+	ev = NewEventDef[mapperTestBig]("MAPPER_TEST_BIG").Event(mapperTestBig{Amount: amount, Nested: struct {
+		Total *big.Int `json:"total"`
+	}{amount}})
+	if ev.Props["amount"] != "9007199254740993" || ev.Etc["nested"] == nil {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func BenchmarkStructToEventProps(b *testing.B) {
+	props := mapperTestProps{UserID: 42, Amount: 9.99, At: mapperTestTime, Latency: time.Second}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := StructToEventProps(props); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStructToProps(b *testing.B) {
+	props := mapperTestProps{UserID: 42, Amount: 9.99, At: mapperTestTime, Latency: time.Second}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := StructToProps(props); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
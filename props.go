@@ -16,35 +16,12 @@ func (p Props) ToEventProps() (map[string]string, map[string]float64, map[string
 	etcProps := map[string]interface{}{}
 
 	for k, v := range (map[string]interface{})(p) {
-		switch t := v.(type) {
-		case string:
-			strProps[k] = t
-		case fmt.Stringer:
-			strProps[k] = t.String()
-		case []byte:
-			strProps[k] = string(t)
-		case int:
-			numProps[k] = float64(t)
-		case int16:
-			numProps[k] = float64(t)
-		case int32:
-			numProps[k] = float64(t)
-		case int64:
-			numProps[k] = float64(t)
-		case uint:
-			numProps[k] = float64(t)
-		case uint16:
-			numProps[k] = float64(t)
-		case uint32:
-			numProps[k] = float64(t)
-		case uint64:
-			numProps[k] = float64(t)
-		case float32:
-			numProps[k] = float64(t)
-		case float64:
-			numProps[k] = t
-		case bool:
-			strProps[k] = fmt.Sprintf("%v", t)
+		str, num, kind := propValue(v)
+		switch kind {
+		case propStr:
+			strProps[k] = str
+		case propNum:
+			numProps[k] = num
 		default:
 			etcProps[k] = v
 		}
@@ -53,6 +30,51 @@ func (p Props) ToEventProps() (map[string]string, map[string]float64, map[string
 	return strProps, numProps, etcProps
 }
 
+// ToFlatEventProps is like ToEventProps, but flattens nested maps, slices
+// and structs into dotted keys instead of returning them as etc props. It
+// returns the keys which were dropped because of the limits of `opts`.
+// Values are converted like the fields of StructToEventProps.
+func (p Props) ToFlatEventProps(opts FlattenOptions) (map[string]string, map[string]float64, []string) {
+	return flattenProps(map[string]interface{}(p), opts)
+}
+
+// propValue converts a prop value to a string or number, or returns
+// propAuto if the value is neither.
+func propValue(v interface{}) (string, float64, propKind) {
+	switch t := v.(type) {
+	case string:
+		return t, 0, propStr
+	case fmt.Stringer:
+		return t.String(), 0, propStr
+	case []byte:
+		return string(t), 0, propStr
+	case int:
+		return "", float64(t), propNum
+	case int16:
+		return "", float64(t), propNum
+	case int32:
+		return "", float64(t), propNum
+	case int64:
+		return "", float64(t), propNum
+	case uint:
+		return "", float64(t), propNum
+	case uint16:
+		return "", float64(t), propNum
+	case uint32:
+		return "", float64(t), propNum
+	case uint64:
+		return "", float64(t), propNum
+	case float32:
+		return "", float64(t), propNum
+	case float64:
+		return "", t, propNum
+	case bool:
+		return fmt.Sprintf("%v", t), 0, propStr
+	default:
+		return "", 0, propAuto
+	}
+}
+
 type ToEventProps interface {
 	ToEventProps() (map[string]string, map[string]float64, map[string]interface{})
 }
@@ -72,3 +94,10 @@ func StructToProps(v any) (map[string]string, map[string]float64, map[string]int
 	strProps, numProps, etcProps := values.ToEventProps()
 	return strProps, numProps, etcProps, nil
 }
+
+// StructToFlatProps is like StructToProps, but flattens nested values into
+// dotted keys per `opts`, see Props.ToFlatEventProps. It maps structs with
+// reflection like StructToEventProps, and also accepts maps.
+func StructToFlatProps(v any, opts FlattenOptions) (map[string]string, map[string]float64, []string, error) {
+	return structToFlatProps(v, opts)
+}
@@ -13,8 +13,15 @@ import (
 const databeatPathPrefix = "/rpc/Databeat/"
 
 // ProxyHandler routes requests from /rpc/Databeat/* to the remote Databeat server.
-func ProxyHandler(databeatHost string) func(next http.Handler) http.Handler {
+// Optionally pass BotOptions to drop or sample requests made by bots. The
+// events of the proxied requests are not enriched, so BotPolicyTag records
+// them like BotPolicyAllow.
+func ProxyHandler(databeatHost string, botOptions ...BotOptions) func(next http.Handler) http.Handler {
 	origin, err := url.Parse(databeatHost)
+	bots := BotOptions{Policy: BotPolicyAllow}
+	if len(botOptions) > 0 {
+		bots = botOptions[0]
+	}
 
 	// In case of an error with the host, we return a handler that does nothing.
 	if databeatHost == "" || err != nil {
@@ -62,6 +69,13 @@ func ProxyHandler(databeatHost string) func(next http.Handler) http.Handler {
 				return
 			}
 
+			// Respond to the frontend without recording bot traffic.
+			if ok, _ := bots.check(r); !ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"ok":true}`))
+				return
+			}
+
 			// Route request to the origin.
 			proxy.ServeHTTP(w, r)
 		})
@@ -0,0 +1,46 @@
+package databeat
+
+import (
+	"maps"
+	"time"
+)
+
+// EventToRaw converts an event to a raw event of `app` at `ts`, which may
+// be nil. The device is flattened to the Device* fields, where empty values
+// are nil, and the props are copied.
+func EventToRaw(ev Event, app *string, ts *time.Time) RawEvent {
+	raw := RawEvent{
+		App:         app,
+		TS:          ts,
+		Event:       ev.Event,
+		ProjectID:   ev.ProjectID,
+		Source:      ev.Source,
+		Ident:       ev.Ident,
+		UserID:      ev.UserID,
+		SessionID:   ev.SessionID,
+		CountryCode: ev.CountryCode,
+		Props:       maps.Clone(ev.Props),
+		Nums:        maps.Clone(ev.Nums),
+		Etc:         maps.Clone(ev.Etc),
+	}
+	if ev.Device != nil {
+		raw.DeviceType = stringPtr(ev.Device.Type, nil)
+		raw.DeviceOS = stringPtr(ev.Device.OS, nil)
+		raw.DeviceOSVersion = stringPtr(ev.Device.OSVersion, nil)
+		raw.DeviceBrowser = stringPtr(ev.Device.Browser, nil)
+		raw.DeviceBrowserVersion = stringPtr(ev.Device.BrowserVersion, nil)
+	}
+	return raw
+}
+
+// RawToEvent converts a raw event to an event, and returns its app and
+// time, so that EventToRaw(RawToEvent(raw)) is equal to raw, as long as its
+// Device* fields are not set to empty strings. The device is set if any of
+// the Device* fields are, and the props are copied.
+func RawToEvent(raw RawEvent) (Event, *string, *time.Time) {
+	ev := *eventFromRaw(&raw)
+	ev.Props = maps.Clone(ev.Props)
+	ev.Nums = maps.Clone(ev.Nums)
+	ev.Etc = maps.Clone(ev.Etc)
+	return ev, raw.App, raw.TS
+}
@@ -0,0 +1,41 @@
+package databeat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRawRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	tests := map[string]RawEvent{
+		"empty":      {Event: "A"},
+		"deviceType": {Event: "A", DeviceType: String("mobile")},
+		"full": {
+			App:                  String("app"),
+			TS:                   &ts,
+			Event:                "A",
+			ProjectID:            1,
+			Source:               "src",
+			Ident:                2,
+			UserID:               String("u1"),
+			SessionID:            String("s1"),
+			CountryCode:          String("FR"),
+			DeviceType:           String("desktop"),
+			DeviceOS:             String("macOS"),
+			DeviceOSVersion:      String("14"),
+			DeviceBrowser:        String("Chrome"),
+			DeviceBrowserVersion: String("124"),
+			Props:                map[string]string{"a": "b"},
+			Nums:                 map[string]float64{"n": 1},
+		},
+	}
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			ev, app, ts := RawToEvent(raw)
+			if got := EventToRaw(ev, app, ts); !reflect.DeepEqual(got, raw) {
+				t.Errorf("EventToRaw(RawToEvent(raw)) = %+v, want %+v", got, raw)
+			}
+		})
+	}
+}
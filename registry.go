@@ -0,0 +1,124 @@
+package databeat
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry is the set of event types allowed to be tracked. It can be
+// updated at runtime, and supports prefix patterns such as "checkout.*".
+//
+// A registry is disabled, and allows all event types, until event types are
+// first added. The event types of EventDefs are then added as well, see
+// RegisteredEventTypes. Removing all event types does not disable it.
+type Registry struct {
+	mu       sync.RWMutex
+	enabled  bool
+	types    map[string]struct{}
+	prefixes map[string]struct{}
+}
+
+func NewRegistry(eventTypes ...string) *Registry {
+	r := &Registry{types: map[string]struct{}{}, prefixes: map[string]struct{}{}}
+	r.Add(eventTypes...)
+	return r
+}
+
+// Add adds event types or prefix patterns ending with "*" to the registry,
+// and enables it.
+func (r *Registry) Add(eventTypes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(eventTypes) > 0 && !r.enabled {
+		r.enabled = true
+		eventTypes = append(RegisteredEventTypes(), eventTypes...)
+	}
+	for _, et := range eventTypes {
+		if prefix, ok := strings.CutSuffix(et, "*"); ok {
+			r.prefixes[prefix] = struct{}{}
+		} else if et != "" {
+			r.types[et] = struct{}{}
+		}
+	}
+}
+
+// Remove removes event types or prefix patterns from the registry.
+func (r *Registry) Remove(eventTypes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, et := range eventTypes {
+		if prefix, ok := strings.CutSuffix(et, "*"); ok {
+			delete(r.prefixes, prefix)
+		} else {
+			delete(r.types, et)
+		}
+	}
+}
+
+// Disable disables the registry, allowing all event types until event types
+// are added again.
+func (r *Registry) Disable() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = false
+}
+
+// Enabled reports whether the registry asserts event types.
+func (r *Registry) Enabled() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.enabled
+}
+
+// Allowed reports whether the event type is in the registry, or if the
+// registry is disabled.
+func (r *Registry) Allowed(eventType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.allowed(eventType)
+}
+
+func (r *Registry) allowed(eventType string) bool {
+	if !r.enabled {
+		return true
+	}
+	if _, ok := r.types[eventType]; ok {
+		return true
+	}
+	for prefix := range r.prefixes {
+		if strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of event types and patterns in the registry.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.types) + len(r.prefixes)
+}
+
+// Types returns the sorted event types and patterns of the registry,
+// ie. for tooling and docs.
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.types)+len(r.prefixes))
+	for et := range r.types {
+		types = append(types, et)
+	}
+	for prefix := range r.prefixes {
+		types = append(types, prefix+"*")
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Registry returns the event type registry of the client, which may be
+// updated at runtime.
+func (t *Databeat) Registry() *Registry {
+	return t.registry
+}
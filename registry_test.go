@@ -0,0 +1,38 @@
+package databeat
+
+import "testing"
+
+type registryTestProps struct {
+	Method string `databeat:"method"`
+}
+
+var registryTestEvent = NewEventDef[registryTestProps]("REGISTRY_TEST")
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if r.Enabled() || !r.Allowed("ANY") {
+		t.Fatal("empty registry should allow all event types")
+	}
+
+	// Adding types at runtime enables the registry with the EventDef types
+	r.Add("LOGIN", "checkout.*")
+	for _, et := range []string{"LOGIN", "checkout.add_to_cart", registryTestEvent.Name()} {
+		if !r.Allowed(et) {
+			t.Errorf("%s should be allowed", et)
+		}
+	}
+	if r.Allowed("ANY") {
+		t.Error("ANY should not be allowed")
+	}
+
+	// Removing all types keeps assertion enabled
+	r.Remove(r.Types()...)
+	if !r.Enabled() || r.Allowed("LOGIN") {
+		t.Error("registry should fail closed once emptied")
+	}
+
+	r.Disable()
+	if !r.Allowed("ANY") {
+		t.Error("disabled registry should allow all event types")
+	}
+}
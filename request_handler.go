@@ -0,0 +1,177 @@
+package databeat
+
+import (
+	"bufio"
+	"io"
+	mrand "math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/horizon-games/go-databeat/proto"
+)
+
+type RequestHandlerOptions struct {
+	// Event is the name of the tracked events.
+	Event string
+
+	// From extracts the user identity of the request, ie. from a session
+	// cookie or auth token. The request itself is always used as
+	// UserHTTPRequest. The identity is also attached to the request context,
+	// see NewContext.
+	From func(r *http.Request) From
+
+	// SampleRate is the fraction of requests tracked, 0 tracks all requests.
+	SampleRate float64
+
+	// ExcludePaths are paths which are not tracked. A trailing "*" matches
+	// the path as a prefix, ie. "/assets/*".
+	ExcludePaths []string
+
+	// Exclude is an optional func to skip tracking of a request.
+	Exclude func(r *http.Request) bool
+}
+
+var DefaultRequestHandlerOptions = RequestHandlerOptions{
+	Event:        proto.EventType_REQUEST.String(),
+	From:         nil,
+	SampleRate:   0,
+	ExcludePaths: []string{"/favicon.ico", databeatPathPrefix + "*"},
+	Exclude:      nil,
+}
+
+// RequestHandler is a middleware which tracks a REQUEST event for every
+// http request, with the `method`, `route` and `status` props and the
+// `bytes` and `latencyMs` nums.
+func (t *Databeat) RequestHandler(opts ...RequestHandlerOptions) func(next http.Handler) http.Handler {
+	options := DefaultRequestHandlerOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.Event == "" {
+		options.Event = DefaultRequestHandlerOptions.Event
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !t.Enabled || options.skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var from From
+			if options.From != nil {
+				from = options.From(r)
+			}
+			from.UserHTTPRequest = r
+			r = r.WithContext(NewContext(r.Context(), from))
+			from, _ = FromContext(r.Context())
+
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+			latency := time.Since(start)
+
+			// The request passed down is the one routed by the mux
+			from.UserHTTPRequest = r
+			route := SourceFromRequest(r, t.options.Source)
+			if rw.status == 0 {
+				rw.status = http.StatusOK
+			}
+
+			t.TrackEvent(from, Event{
+				Event: options.Event,
+				Props: map[string]string{
+					"method": r.Method,
+					"route":  route,
+					"status": strconv.Itoa(rw.status),
+				},
+				Nums: map[string]float64{
+					"bytes":     float64(rw.bytes),
+					"latencyMs": float64(latency.Microseconds()) / 1000,
+				},
+			})
+		})
+	}
+}
+
+func (o RequestHandlerOptions) skip(r *http.Request) bool {
+	for _, p := range o.ExcludePaths {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return true
+			}
+		} else if r.URL.Path == p {
+			return true
+		}
+	}
+	if o.Exclude != nil && o.Exclude(r) {
+		return true
+	}
+	if o.SampleRate > 0 && o.SampleRate < 1 && mrand.Float64() >= o.SampleRate {
+		return true
+	}
+	return false
+}
+
+// responseWriter records the status code and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection, ie. for WebSocket upgrades, which are
+// recorded with status 101.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// ReadFrom keeps the sendfile optimization of http.ServeContent.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,169 @@
+package databeat
+
+import "net/http"
+
+// Scope is a tracker derived from a Databeat client with With, similar to
+// slog.Logger.With. It merges its default props, nums, source and origin
+// into every event it tracks, while sharing the queue and workers of the
+// client. Values set on an event always take precedence.
+//
+// The default props of a scope are exempt from the unknown prop check of
+// Options.Validation schemas, like the props added by enrichers.
+type Scope struct {
+	t        *Databeat
+	from     From
+	props    map[string]string
+	nums     map[string]float64
+	defaults map[string]struct{}
+}
+
+// With returns a Scope which tracks events with the default `props` and
+// origin `from`. String-like values of `props` are set on Event.Props and
+// numeric values on Event.Nums.
+func (t *Databeat) With(props Props, from From) *Scope {
+	return (&Scope{t: t}).With(props, from)
+}
+
+// With returns a nested Scope, where `props` and non-zero fields of
+// `from` override the ones of the parent scope. Nested values of `props`
+// are flattened per Options.Flatten.
+func (s *Scope) With(props Props, from From) *Scope {
+	strProps, numProps, _ := props.ToFlatEventProps(s.t.options.Flatten)
+	scope := &Scope{
+		t:        s.t,
+		from:     mergeFrom(s.from, from),
+		props:    mergeMaps(s.props, strProps),
+		nums:     mergeMaps(s.nums, numProps),
+		defaults: map[string]struct{}{},
+	}
+	for k := range scope.props {
+		scope.defaults[k] = struct{}{}
+	}
+	for k := range scope.nums {
+		scope.defaults[k] = struct{}{}
+	}
+	return scope
+}
+
+// Client returns the Databeat client of the scope.
+func (s *Scope) Client() *Databeat {
+	return s.t
+}
+
+// TrackEvent tracks the events, where non-zero fields of `from` override
+// the origin of the scope.
+func (s *Scope) TrackEvent(from From, trackEvents ...Event) {
+	if !s.t.Enabled {
+		return
+	}
+	from = mergeFrom(s.from, from)
+	events := make([]*Event, len(trackEvents))
+	for i, ev := range trackEvents {
+		v := ev // copy
+		s.applyDefaults(&v, from)
+		events[i] = &v
+	}
+	s.t.track(s.t.enrich(from, s.t.validate(events, s.defaults)))
+}
+
+// TrackUserEvent tracks the events associated to a particular user, see
+// Databeat.TrackUserEvent.
+func (s *Scope) TrackUserEvent(r *http.Request, userID string, userEvents ...Event) {
+	s.TrackEvent(From{UserID: userID, UserHTTPRequest: r}, userEvents...)
+}
+
+// Track tracks the events with the default props, nums, source and project
+// of the scope. Like Databeat.Track, user details of the scope are not used.
+func (s *Scope) Track(events ...*Event) {
+	if !s.t.Enabled {
+		return
+	}
+	for _, ev := range events {
+		s.applyDefaults(ev, s.from)
+	}
+	s.t.track(s.t.enrich(From{}, s.t.validate(events, s.defaults)))
+}
+
+// TrackRaw tracks the raw events with the default props, nums, source and
+// project of the scope.
+func (s *Scope) TrackRaw(events ...*RawEvent) {
+	if !s.t.Enabled {
+		return
+	}
+	for _, raw := range events {
+		ev := eventFromRaw(raw)
+		s.applyDefaults(ev, s.from)
+		copyEventToRaw(ev, raw)
+	}
+	s.t.trackRaw(s.t.enrichRaw(From{}, s.t.validateRaw(events, s.defaults)))
+}
+
+// TrackRawEvent tracks the raw events with the defaults of the scope, see
+// Databeat.TrackRawEvent.
+func (s *Scope) TrackRawEvent(from From, trackEvents ...RawEvent) {
+	if !s.t.Enabled {
+		return
+	}
+	from = mergeFrom(s.from, from)
+	events := make([]*RawEvent, len(trackEvents))
+	for i, raw := range trackEvents {
+		v := raw // copy
+		ev := eventFromRaw(&v)
+		s.applyDefaults(ev, from)
+		copyEventToRaw(ev, &v)
+		events[i] = &v
+	}
+	s.t.trackRaw(s.t.enrichRaw(from, s.t.validateRaw(events, s.defaults)))
+}
+
+// applyDefaults sets the default props and nums of the scope on the event,
+// and the source and project of `from`, the origin merged with the scope.
+func (s *Scope) applyDefaults(ev *Event, from From) {
+	if len(s.props) > 0 {
+		ev.Props = mergeMaps(s.props, ev.Props)
+	}
+	if len(s.nums) > 0 {
+		ev.Nums = mergeMaps(s.nums, ev.Nums)
+	}
+	if ev.Source == "" {
+		ev.Source = from.Source
+	}
+	if ev.ProjectID == 0 {
+		ev.ProjectID = from.ProjectID
+	}
+}
+
+// mergeFrom returns `parent` with the non-zero fields of `child`.
+func mergeFrom(parent, child From) From {
+	if child.UserID != "" {
+		parent.UserID = child.UserID
+	}
+	if child.UserHTTPRequest != nil {
+		parent.UserHTTPRequest = child.UserHTTPRequest
+	}
+	if child.ProjectID != 0 {
+		parent.ProjectID = child.ProjectID
+	}
+	if child.Source != "" {
+		parent.Source = child.Source
+	}
+	if child.SessionID != "" {
+		parent.SessionID = child.SessionID
+	}
+	return parent
+}
+
+// mergeMaps returns a new map with the values of `b` over the values of `a`.
+func mergeMaps[V any](a, b map[string]V) map[string]V {
+	if len(a) == 0 && len(b) == 0 {
+		return b
+	}
+	m := make(map[string]V, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
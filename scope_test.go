@@ -0,0 +1,26 @@
+package databeat
+
+import "testing"
+
+func TestScopeFromOverride(t *testing.T) {
+	var captured []Event
+	dbeat := newCaptureClient(t, DefaultOptions, &captured, DefaultEnrichers...)
+
+	scope := dbeat.With(Props{"tenant": "acme"}, From{Source: "scope-src", ProjectID: 1})
+	scope.TrackEvent(From{Source: "call-src"}, Event{Event: "A"})
+	scope.TrackEvent(From{}, Event{Event: "B"})
+	scope.TrackEvent(From{}, Event{Event: "C", Source: "event-src"})
+
+	want := []string{"call-src", "scope-src", "event-src"}
+	if len(captured) != len(want) {
+		t.Fatalf("got %d events, want %d", len(captured), len(want))
+	}
+	for i, ev := range captured {
+		if ev.Source != want[i] {
+			t.Errorf("event %s: got source %q, want %q", ev.Event, ev.Source, want[i])
+		}
+		if ev.ProjectID != 1 || ev.Props["tenant"] != "acme" {
+			t.Errorf("event %s: scope defaults not applied: %+v", ev.Event, ev)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package databeat
+
+import (
+	"sync"
+)
+
+// SequenceOptions configures the sequence numbers of events, which let
+// downstream consumers order events and detect gaps.
+type SequenceOptions struct {
+	// Enabled sets increasing sequence numbers on events when tracked, on
+	// the ClientProp num per client instance, and on the SessionProp num
+	// per session, or per user for events without a session id. It is off
+	// by default, as it adds nums to every event.
+	Enabled bool
+
+	// ClientProp is the num of the client sequence number.
+	ClientProp string
+
+	// SessionProp is the num of the session sequence number.
+	SessionProp string
+
+	// MaxSessions is the number of sessions with sequence numbers kept in
+	// memory. The sequence of the oldest session restarts if it is tracked
+	// again after being evicted.
+	MaxSessions int
+
+	// OrderedDelivery delivers events in the order they are tracked, per
+	// Event and RawEvent queue. Batches are sent one at a time instead of
+	// FlushConcurrency, and failed batches are re-queued ahead of newer
+	// events with the batches which follow them.
+	OrderedDelivery bool
+}
+
+var DefaultSequenceOptions = SequenceOptions{
+	Enabled: false, ClientProp: "_seq", SessionProp: "_sseq", MaxSessions: 10_000, OrderedDelivery: false,
+}
+
+type sequencer struct {
+	mu       sync.Mutex
+	client   uint64
+	sessions *boundedMap[uint64]
+}
+
+func newSequencer(max int) *sequencer {
+	if max <= 0 {
+		max = DefaultSequenceOptions.MaxSessions
+	}
+	return &sequencer{sessions: newBoundedMap[uint64](max)}
+}
+
+// next returns the next client sequence number, and the next sequence
+// number of the session if not empty.
+func (s *sequencer) next(session string) (uint64, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.client++
+	if session == "" {
+		return s.client, 0
+	}
+
+	seq, _ := s.sessions.get(session)
+	seq++
+	s.sessions.set(session, seq)
+	return s.client, seq
+}
+
+// sequenceKey returns the session id, or the user id for events without
+// a session.
+func sequenceKey(sessionID, userID *string) string {
+	if session := derefString(sessionID); session != "" {
+		return "s:" + session
+	}
+	if user := derefString(userID); user != "" {
+		return "u:" + user
+	}
+	return ""
+}
+
+func (t *Databeat) setSequenceNums(nums map[string]float64, session string) map[string]float64 {
+	opts := t.options.Sequence
+	clientSeq, sessionSeq := t.sequencer.next(session)
+	if nums == nil {
+		nums = map[string]float64{}
+	}
+	if opts.ClientProp != "" {
+		nums[opts.ClientProp] = float64(clientSeq)
+	}
+	if opts.SessionProp != "" && sessionSeq > 0 {
+		nums[opts.SessionProp] = float64(sessionSeq)
+	}
+	return nums
+}
+
+// sequenceEvents sets the sequence numbers of events, see SequenceOptions.
+// It is called with t.mu held, so sequence numbers follow the queue order.
+func (t *Databeat) sequenceEvents(events []*Event) {
+	if !t.options.Sequence.Enabled {
+		return
+	}
+	for _, ev := range events {
+		ev.Nums = t.setSequenceNums(ev.Nums, sequenceKey(ev.SessionID, ev.UserID))
+	}
+}
+
+// sequenceRawEvents sets the sequence numbers of raw events, see
+// SequenceOptions.
+func (t *Databeat) sequenceRawEvents(events []*RawEvent) {
+	if !t.options.Sequence.Enabled {
+		return
+	}
+	for _, ev := range events {
+		ev.Nums = t.setSequenceNums(ev.Nums, sequenceKey(ev.SessionID, ev.UserID))
+	}
+}
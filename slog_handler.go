@@ -0,0 +1,178 @@
+package databeat
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+type SlogHandlerOptions struct {
+	// Level tracks every record at or above the level.
+	Level slog.Leveler
+
+	// MessagePrefix tracks records whose message starts with the prefix,
+	// ie. "event:". The prefix is trimmed from the event name.
+	MessagePrefix string
+
+	// MarkerKey tracks records carrying an attribute with the key, ie.
+	// slog.Bool("databeat", true). The marker is not added to the props.
+	MarkerKey string
+
+	// MinLevel is the minimum level of records considered for tracking.
+	// Defaults to slog.LevelInfo.
+	MinLevel slog.Leveler
+}
+
+// SlogHandler is a slog.Handler which tracks the records matching its
+// options as events, and forwards every record to the wrapped handler.
+// The record message is the event name, and its attributes are flattened
+// into Props and Nums with dotted keys for groups and nested values. The
+// tracking identity is read from the record context, see NewContext.
+type SlogHandler struct {
+	t        *Databeat
+	next     slog.Handler
+	opts     SlogHandlerOptions
+	group    string
+	props    Props
+	marked   bool
+	internal bool
+}
+
+var _ slog.Handler = &SlogHandler{}
+
+// NewSlogHandler returns a handler tracking records to `dbeat`. The `next`
+// handler is optional.
+func NewSlogHandler(dbeat *Databeat, next slog.Handler, opts SlogHandlerOptions) *SlogHandler {
+	if opts.MinLevel == nil {
+		opts.MinLevel = slog.LevelInfo
+	}
+	return &SlogHandler{t: dbeat, next: next, opts: opts, props: Props{}}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.next != nil && h.next.Enabled(ctx, level) {
+		return true
+	}
+	return !h.internal && level >= h.opts.MinLevel.Level()
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if name, ok := h.match(r); ok {
+		h.track(ctx, name, r)
+	}
+	if h.next != nil && h.next.Enabled(ctx, r.Level) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := h.clone()
+	for _, a := range attrs {
+		// Skip the logs of the databeat client itself, to avoid feedback loops
+		if a.Key == "ps" && a.Value.String() == "databeat" {
+			h2.internal = true
+		}
+		if h.group == "" && h.opts.MarkerKey != "" && a.Key == h.opts.MarkerKey {
+			h2.marked = true
+			continue
+		}
+		flattenSlogAttr(h2.props, h.group, a)
+	}
+	if h.next != nil {
+		h2.next = h.next.WithAttrs(attrs)
+	}
+	return h2
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.group = h.group + name + "."
+	if h.next != nil {
+		h2.next = h.next.WithGroup(name)
+	}
+	return h2
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	h2 := *h
+	h2.props = make(Props, len(h.props))
+	for k, v := range h.props {
+		h2.props[k] = v
+	}
+	return &h2
+}
+
+// match returns the event name of the record, if it should be tracked.
+func (h *SlogHandler) match(r slog.Record) (string, bool) {
+	if h.internal || r.Level < h.opts.MinLevel.Level() {
+		return "", false
+	}
+	if h.opts.MessagePrefix != "" && strings.HasPrefix(r.Message, h.opts.MessagePrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(r.Message, h.opts.MessagePrefix)), true
+	}
+	if h.opts.Level != nil && r.Level >= h.opts.Level.Level() {
+		return r.Message, true
+	}
+	if h.opts.MarkerKey != "" {
+		marked := h.marked
+		if !marked && h.group == "" {
+			r.Attrs(func(a slog.Attr) bool {
+				marked = a.Key == h.opts.MarkerKey
+				return !marked
+			})
+		}
+		if marked {
+			return r.Message, true
+		}
+	}
+	return "", false
+}
+
+func (h *SlogHandler) track(ctx context.Context, name string, r slog.Record) {
+	if name == "" {
+		return
+	}
+
+	props := make(Props, len(h.props)+r.NumAttrs())
+	for k, v := range h.props {
+		props[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if !(h.group == "" && a.Key == h.opts.MarkerKey) {
+			flattenSlogAttr(props, h.group, a)
+		}
+		return true
+	})
+	strProps, numProps, _ := props.ToFlatEventProps(h.t.options.Flatten)
+
+	from, _ := FromContext(ctx)
+	h.t.TrackEvent(from, Event{
+		Event: name,
+		Props: strProps,
+		Nums:  numProps,
+	})
+}
+
+// flattenSlogAttr adds the attribute to props, with groups flattened into
+// dotted keys.
+func flattenSlogAttr(props Props, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		group := prefix
+		if a.Key != "" {
+			group = prefix + a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			flattenSlogAttr(props, group, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	props[prefix+a.Key] = v.Any()
+}
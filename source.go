@@ -0,0 +1,137 @@
+package databeat
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RoutePatternFunc returns the route pattern matched by a router for the
+// request, or an empty string. For example with chi:
+//
+//	func(r *http.Request) string {
+//		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+//			return rctx.RoutePattern()
+//		}
+//		return ""
+//	}
+//
+// or with gorilla/mux:
+//
+//	func(r *http.Request) string {
+//		tmpl, _ := mux.CurrentRoute(r).GetPathTemplate()
+//		return tmpl
+//	}
+type RoutePatternFunc func(r *http.Request) string
+
+type SourceOptions struct {
+	// RoutePatterns are extractors for router specific route patterns, used
+	// when the request was not routed by http.ServeMux.
+	RoutePatterns []RoutePatternFunc
+
+	// Normalize replaces numeric ids, UUIDs and hex hashes in the request
+	// path with placeholders, if no route pattern is known.
+	Normalize bool
+}
+
+var DefaultSourceOptions = SourceOptions{
+	RoutePatterns: nil, Normalize: true,
+}
+
+// SourceFromRequest returns the source of a request. It is the route
+// pattern of http.ServeMux (Go 1.22+) or of the RoutePatterns extractors,
+// and otherwise the normalized request path.
+func SourceFromRequest(r *http.Request, opts SourceOptions) string {
+	if r == nil {
+		return ""
+	}
+	if r.Pattern != "" {
+		return patternPath(r.Pattern)
+	}
+	for _, fn := range opts.RoutePatterns {
+		if pattern := fn(r); pattern != "" {
+			return pattern
+		}
+	}
+	if opts.Normalize {
+		return NormalizePath(r.URL.Path)
+	}
+	return r.URL.Path
+}
+
+// patternPath strips the method and host of a http.ServeMux pattern,
+// ie. "GET example.com/users/{id}" becomes "/users/{id}".
+func patternPath(pattern string) string {
+	if _, p, ok := strings.Cut(pattern, " "); ok {
+		pattern = strings.TrimSpace(p)
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// NormalizePath replaces the path segments which look like identifiers
+// with the placeholders {id}, {uuid} and {hash}, so that
+// "/users/123/orders/9b2c..." becomes "/users/{id}/orders/{hash}".
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		switch {
+		case seg == "":
+		case isNumeric(seg):
+			segments[i] = "{id}"
+		case isUUID(seg):
+			segments[i] = "{uuid}"
+		case isHexHash(seg):
+			segments[i] = "{hash}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isNumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isHexHash reports whether s is a hex string of at least 16 digits, with
+// an optional 0x prefix such as hashes, object ids and addresses.
+func isHexHash(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s) < 16 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isHexDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,257 @@
+package databeat
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+)
+
+type PropType uint8
+
+const (
+	PropTypeAny PropType = iota
+	PropTypeString
+	PropTypeNumber
+)
+
+// PropRule is the validation rule of a single prop.
+type PropRule struct {
+	Type PropType
+
+	// Required fails events without the prop. Props set by enrichers, ie.
+	// `service` of GlobalContextEnricher, can not be required, as events
+	// are validated before they are enriched.
+	Required bool
+
+	Enum      []string
+	MaxLength int
+}
+
+// EventSchema is the validation schema of the props of an event type.
+// String props are read from Event.Props and numbers from Event.Nums, as
+// tracked by the caller, with the default props of a Scope.
+type EventSchema struct {
+	Props map[string]PropRule
+
+	// AllowUnknownProps accepts props which are not in the schema.
+	AllowUnknownProps bool
+}
+
+type ValidationMode uint8
+
+const (
+	// ValidationReport reports invalid events, and tracks them unchanged.
+	ValidationReport ValidationMode = iota
+
+	// ValidationStrip removes invalid props from events. Events missing
+	// required props are dropped.
+	ValidationStrip
+
+	// ValidationDrop drops invalid events.
+	ValidationDrop
+)
+
+// ValidationOptions configures the validation of tracked events.
+//
+// Events are validated as tracked by the caller, before they are enriched,
+// so invalid events are reported against the props the caller set, and
+// dropped events are not enriched. Props set by enrichers are neither
+// checked nor reported as unknown, and can not be required by a schema.
+type ValidationOptions struct {
+	// Schemas are the prop schemas by event type. Events without a schema
+	// are not validated.
+	Schemas map[string]EventSchema
+
+	// Mode is the action taken on invalid events.
+	Mode ValidationMode
+}
+
+var DefaultValidationOptions = ValidationOptions{
+	Schemas: nil, Mode: ValidationReport,
+}
+
+const (
+	ReasonUnknownEvent = "unknown_event"
+	ReasonMissing      = "missing"
+	ReasonUnknownProp  = "unknown_prop"
+	ReasonType         = "type"
+	ReasonEnum         = "enum"
+	ReasonMaxLength    = "max_length"
+)
+
+type ValidationError struct {
+	Event  string
+	Prop   string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	if e.Prop == "" {
+		return fmt.Sprintf("databeat: event %q: %s", e.Event, e.Reason)
+	}
+	return fmt.Sprintf("databeat: event %q: prop %q: %s", e.Event, e.Prop, e.Reason)
+}
+
+// Validate returns the validation errors of the event props.
+func (s EventSchema) Validate(ev *Event) []ValidationError {
+	return s.validate(ev, nil)
+}
+
+// validate returns the validation errors of the event props, where the
+// `defaults` props, ie. of a Scope, are exempt from the unknown prop check.
+func (s EventSchema) validate(ev *Event, defaults map[string]struct{}) []ValidationError {
+	var errs []ValidationError
+	invalid := func(prop, reason string) {
+		errs = append(errs, ValidationError{Event: ev.Event, Prop: prop, Reason: reason})
+	}
+
+	for name, rule := range s.Props {
+		str, isStr := ev.Props[name]
+		_, isNum := ev.Nums[name]
+
+		switch {
+		case !isStr && !isNum:
+			if rule.Required {
+				invalid(name, ReasonMissing)
+			}
+			continue
+		case rule.Type == PropTypeString && !isStr, rule.Type == PropTypeNumber && !isNum:
+			invalid(name, ReasonType)
+			continue
+		}
+
+		if isStr {
+			if rule.MaxLength > 0 && len(str) > rule.MaxLength {
+				invalid(name, ReasonMaxLength)
+			} else if len(rule.Enum) > 0 && !contains(rule.Enum, str) {
+				invalid(name, ReasonEnum)
+			}
+		}
+	}
+
+	if !s.AllowUnknownProps {
+		for name := range ev.Props {
+			if !s.known(name, defaults) {
+				invalid(name, ReasonUnknownProp)
+			}
+		}
+		for name := range ev.Nums {
+			if !s.known(name, defaults) {
+				invalid(name, ReasonUnknownProp)
+			}
+		}
+	}
+
+	return errs
+}
+
+func (s EventSchema) known(name string, defaults map[string]struct{}) bool {
+	if _, ok := s.Props[name]; ok {
+		return true
+	}
+	_, ok := defaults[name]
+	return ok
+}
+
+// validate checks the event types and prop schemas of the events before
+// they are enriched, and returns the events to track per the validation mode.
+// The `defaults` props are exempt from the unknown prop check, see Scope.
+func (t *Databeat) validate(events []*Event, defaults map[string]struct{}) []*Event {
+	var errs []ValidationError
+
+	// Flatten nested values first, so they are validated like other props
+	if t.options.Flatten.Enabled {
+		t.flattenEtc(events)
+	}
+
+	// Validate event types at runtime if the registry is enabled
+	if t.registry.Enabled() {
+		var valid bool
+		var invalidNames []string
+		valid, invalidNames, events = validateEventTypes(t.registry, events)
+		if !valid {
+			t.log.Warn(fmt.Sprintf("databeat: %d invalid event types", len(invalidNames)), slog.Any("invalidEvents", invalidNames))
+			for _, name := range invalidNames {
+				errs = append(errs, ValidationError{Event: name, Reason: ReasonUnknownEvent})
+			}
+			t.alerts.drop(ReasonUnknownEvent, len(invalidNames), invalidNames)
+		}
+	}
+
+	// Validate props of event types with a schema
+	if schemas := t.options.Validation.Schemas; len(schemas) > 0 {
+		valid := events[:0:0]
+		var dropped []*Event
+		numInvalid := 0
+		for _, ev := range events {
+			schema, ok := schemas[ev.Event]
+			if !ok {
+				valid = append(valid, ev)
+				continue
+			}
+			evErrs := schema.validate(ev, defaults)
+			if len(evErrs) == 0 {
+				valid = append(valid, ev)
+				continue
+			}
+			numInvalid++
+			errs = append(errs, evErrs...)
+
+			switch t.options.Validation.Mode {
+			case ValidationDrop:
+				dropped = append(dropped, ev)
+				continue
+			case ValidationStrip:
+				if !stripInvalidProps(ev, schema, evErrs) {
+					dropped = append(dropped, ev)
+					continue
+				}
+			}
+			valid = append(valid, ev)
+		}
+		if numInvalid > 0 {
+			t.log.Warn(fmt.Sprintf("databeat: %d events with invalid props", numInvalid))
+		}
+		if len(dropped) > 0 {
+			t.alerts.drop(ReasonValidation, len(dropped), eventNames(dropped))
+		}
+		events = valid
+	}
+
+	if len(errs) > 0 {
+		t.alerts.invalid(errs)
+	}
+
+	return events
+}
+
+// stripInvalidProps removes the invalid props of the event, and returns
+// false if a required prop is missing or invalid.
+func stripInvalidProps(ev *Event, schema EventSchema, errs []ValidationError) bool {
+	props, nums := maps.Clone(ev.Props), maps.Clone(ev.Nums)
+	for _, err := range errs {
+		if err.Reason == ReasonMissing || schema.Props[err.Prop].Required {
+			return false
+		}
+		delete(props, err.Prop)
+		delete(nums, err.Prop)
+	}
+	ev.Props, ev.Nums = props, nums
+	return true
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRaw validates raw events through their Event view.
+func (t *Databeat) validateRaw(rawEvents []*RawEvent, defaults map[string]struct{}) []*RawEvent {
+	return t.applyToRaw(rawEvents, func(events []*Event) []*Event {
+		return t.validate(events, defaults)
+	})
+}
@@ -0,0 +1,46 @@
+package databeat
+
+import "testing"
+
+func TestValidationScopeDefaults(t *testing.T) {
+	var captured []Event
+	opts := DefaultOptions
+	opts.Validation = ValidationOptions{
+		Schemas: map[string]EventSchema{
+			"LOGIN": {Props: map[string]PropRule{"method": {Type: PropTypeString, Required: true}}},
+		},
+		Mode: ValidationDrop,
+	}
+	dbeat := newCaptureClient(t, opts, &captured)
+	scope := dbeat.With(Props{"tenant": "acme", "shard": 3}, From{})
+
+	scope.TrackEvent(From{}, Event{Event: "LOGIN", Props: map[string]string{"method": "email"}})
+	scope.TrackEvent(From{}, Event{Event: "LOGIN", Props: map[string]string{"method": "email", "other": "x"}})
+	dbeat.TrackEvent(From{}, Event{Event: "LOGIN", Props: map[string]string{"method": "email", "tenant": "acme"}})
+
+	if len(captured) != 1 {
+		t.Fatalf("got %d events, want 1", len(captured))
+	}
+	if captured[0].Props["tenant"] != "acme" || captured[0].Nums["shard"] != 3 {
+		t.Errorf("scope defaults not applied: %+v", captured[0])
+	}
+}
+
+func TestValidationBeforeEnrich(t *testing.T) {
+	var captured []Event
+	opts := DefaultOptions
+	opts.GlobalContext.Props = map[string]string{"region": "eu"}
+	opts.Validation = ValidationOptions{
+		Schemas: map[string]EventSchema{
+			"LOGIN": {Props: map[string]PropRule{"method": {Type: PropTypeString}}},
+		},
+		Mode: ValidationDrop,
+	}
+	dbeat := newCaptureClient(t, opts, &captured, GlobalContextEnricher)
+
+	dbeat.TrackEvent(From{}, Event{Event: "LOGIN", Props: map[string]string{"method": "email"}})
+
+	if len(captured) != 1 || captured[0].Props["region"] != "eu" {
+		t.Errorf("enricher props should not be validated, got %+v", captured)
+	}
+}